@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 	"unsafe"
 )
 
@@ -48,6 +49,14 @@ func (e *implementationActivator) activate(ctx resolveContext) (any, error) {
 	return instance, nil
 }
 
+func (e *implementationActivator) dependencies() ([]dependency, error) {
+	return []dependency{}, nil
+}
+
+func (e *implementationActivator) instanceType() reflect.Type {
+	return e.implementationType
+}
+
 type instanceActivator struct {
 	instance any
 }
@@ -76,11 +85,40 @@ func (e *instanceActivator) activate(ctx resolveContext) (any, error) {
 	return e.instance, nil
 }
 
+func (e *instanceActivator) dependencies() ([]dependency, error) {
+	return []dependency{}, nil
+}
+
+func (e *instanceActivator) instanceType() reflect.Type {
+	return reflect.TypeOf(e.instance)
+}
+
+// declaredActivator stands for the instances declared with DeclareScopedInstance, which are provided by the scopes.
+// It is only looked up by the validation and the graph, so it is never activated by the resolutions.
+type declaredActivator struct {
+	serviceType reflect.Type
+}
+
+func (e *declaredActivator) activate(ctx resolveContext) (any, error) {
+	return nil, ctx.newError(ErrNotRegistered, nil)
+}
+
+func (e *declaredActivator) dependencies() ([]dependency, error) {
+	return []dependency{}, nil
+}
+
+func (e *declaredActivator) instanceType() reflect.Type {
+	return e.serviceType
+}
+
 type constructorActivator struct {
 	constructor any
+	// service keys for the arguments, by argument index
+	argumentKeys map[int]any
+	plan         *functionPlan
 }
 
-func newConstructorActivator[T any, TConstructor any](ctor TConstructor) (activator, error) {
+func newConstructorActivator[T any, TConstructor any](ctor TConstructor, argumentKeys map[int]any) (activator, error) {
 	// check type parameters
 	tRet := typeof[T]()
 	tCtor := typeof[TConstructor]()
@@ -120,46 +158,144 @@ func newConstructorActivator[T any, TConstructor any](ctor TConstructor) (activa
 	if !reflect.ValueOf(ctor).IsValid() || reflect.ValueOf(ctor).IsNil() {
 		return nil, errors.New("ctor is invalid or nil")
 	}
-	return &constructorActivator{constructor: ctor}, nil
+	// check argument keys
+	for index := range argumentKeys {
+		if index < 0 || index >= tCtor.NumIn() {
+			return nil, fmt.Errorf("argument index `%d` is out of range for TConstructor=`%s`", index, nameof[TConstructor]())
+		}
+	}
+	return &constructorActivator{
+		constructor:  ctor,
+		argumentKeys: argumentKeys,
+		plan:         newFunctionPlan(ctor, argumentKeys),
+	}, nil
 }
 
 func (e *constructorActivator) activate(ctx resolveContext) (any, error) {
-	tFn := reflect.TypeOf(e.constructor)
-	vFn := reflect.ValueOf(e.constructor)
-	numArgs := tFn.NumIn()
-	tFnArgs := make([]reflect.Type, numArgs)
-	for i := 0; i < numArgs; i++ {
-		tFnArgs[i] = tFn.In(i)
-	}
 	// constructor injection
-	args := make([]reflect.Value, numArgs)
-	for idx := 0; idx < numArgs; idx++ {
-		instance, err := ctx.resolve(registryKey{
-			serviceType: tFnArgs[idx],
-			serviceKey:  nil, /* no key is available for constructor injection */
-		})
+	return e.plan.call(ctx, ErrConstructorFailed)
+}
+
+// functionPlan is the information to call a function with resolving its arguments,
+// which is computed once on registration.
+type functionPlan struct {
+	fn reflect.Value
+	// registry keys for the arguments, by argument index
+	keys []registryKey
+	// whether the function returns an error as the second return value
+	returnsError bool
+}
+
+func newFunctionPlan(fn any, argumentKeys map[int]any) *functionPlan {
+	tFn := reflect.TypeOf(fn)
+	keys := make([]registryKey, tFn.NumIn())
+	for i := range keys {
+		keys[i] = registryKey{serviceType: tFn.In(i), serviceKey: argumentKeys[i]}
+	}
+	return &functionPlan{fn: reflect.ValueOf(fn), keys: keys, returnsError: tFn.NumOut() == 2}
+}
+
+// Call the function with resolving its arguments, and return the first return value.
+// The first arguments can be given by `args`, and the remaining ones are resolved.
+// If the function returns a non-nil error as the second return value,
+// it is wrapped with the `failure` error.
+func (p *functionPlan) call(ctx resolveContext, failure error, args ...reflect.Value) (any, error) {
+	in := make([]reflect.Value, len(p.keys))
+	copy(in, args)
+	for idx := len(args); idx < len(in); idx++ {
+		instance, err := ctx.resolve(p.keys[idx])
 		if err != nil {
 			return nil, err
 		}
-		args[idx] = reflect.ValueOf(instance)
+		in[idx] = reflect.ValueOf(instance)
 	}
-	ret := vFn.Call(args)
+	ret := p.fn.Call(in)
 	// check error value
-	if len(ret) == 2 && ret[1].IsValid() && !ret[1].IsNil() {
+	if p.returnsError && !ret[1].IsNil() {
 		//nolint:forcetypeassert
 		err := ret[1].Interface().(error)
-		if err != nil {
-			return nil, err
-		}
+		return nil, ctx.newError(failure, err)
 	}
 	instance := ret[0].Interface()
 	return instance, nil
 }
 
+// Returns the dependencies for the arguments, skipping the first `skip` arguments given by the caller.
+func (p *functionPlan) dependencies(skip int) []dependency {
+	ret := make([]dependency, 0, len(p.keys))
+	for i := skip; i < len(p.keys); i++ {
+		ret = append(ret, dependency{
+			key:       p.keys[i],
+			argIndex:  i,
+			fieldName: "",
+			optional:  false,
+		})
+	}
+	return ret
+}
+
+func (e *constructorActivator) dependencies() ([]dependency, error) {
+	return e.plan.dependencies(0), nil
+}
+
+func (e *constructorActivator) instanceType() reflect.Type {
+	return reflect.TypeOf(e.constructor).Out(0)
+}
+
 type fieldInjectionActivator struct {
 	baseActivator activator
 }
 
+// fieldPlan is the information to inject a field, parsed from the struct tag.
+type fieldPlan struct {
+	index  int
+	name   string
+	offset uintptr
+	key    registryKey
+	info   *tagInfo
+}
+
+// structPlan is the information to inject the fields of a struct type.
+type structPlan struct {
+	fields []fieldPlan
+	// the error to parse the struct tags
+	err error
+}
+
+// the plans by struct types, which are computed on the first use
+//
+//nolint:gochecknoglobals
+var structPlans sync.Map
+
+func structPlanOf(t reflect.Type) *structPlan {
+	if plan, ok := structPlans.Load(t); ok {
+		//nolint:forcetypeassert
+		return plan.(*structPlan)
+	}
+	plan := &structPlan{fields: make([]fieldPlan, 0), err: nil}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		info, err := parseTag(field.Tag)
+		if err != nil {
+			plan = &structPlan{fields: nil, err: err}
+			break
+		}
+		if !info.inject {
+			continue
+		}
+		plan.fields = append(plan.fields, fieldPlan{
+			index:  i,
+			name:   field.Name,
+			offset: field.Offset,
+			key:    registryKey{serviceType: field.Type, serviceKey: info.key},
+			info:   info,
+		})
+	}
+	actual, _ := structPlans.LoadOrStore(t, plan)
+	//nolint:forcetypeassert
+	return actual.(*structPlan)
+}
+
 func (e *fieldInjectionActivator) activate(ctx resolveContext) (any, error) {
 	instance, err := e.baseActivator.activate(ctx)
 	if err != nil {
@@ -174,50 +310,95 @@ func (e *fieldInjectionActivator) activate(ctx resolveContext) (any, error) {
 		return instance, nil
 	}
 	// field injection
-	t := val.Type()
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		fieldType := field.Type()
-		info, err := parseTag(t.Field(i).Tag)
-		if err != nil {
-			return nil, err
-		}
-		if !info.inject {
-			continue
-		}
-		if !field.CanSet() {
-			// accessing unexported fields
-			// cf. https://stackoverflow.com/a/43918797
-			field = reflect.NewAt(fieldType, unsafe.Pointer(field.UnsafeAddr())).Elem()
-		}
-		instance, err := ctx.resolve(registryKey{
-			serviceType: fieldType,
-			serviceKey:  info.key,
-		})
-		if err != nil {
-			return nil, err
+	plan := structPlanOf(val.Type())
+	if plan.err != nil {
+		return nil, plan.err
+	}
+	for i := range plan.fields {
+		field := &plan.fields[i]
+		var dep any
+		if field.info.optional {
+			// leave the field as it is if the dependency is not registered
+			instance, ok, err := ctx.resolveOptional(field.key)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			dep = instance
+		} else {
+			instance, err := ctx.resolve(field.key)
+			if err != nil {
+				return nil, err
+			}
+			dep = instance
 		}
-		field.Set(reflect.ValueOf(instance))
+		fieldValue(val, field).Set(reflect.ValueOf(dep))
 	}
 	return instance, nil
 }
 
-type cacheActivator struct {
-	baseActivator activator
-	policy        CachePolicy
+// Returns the settable value of the field, including unexported fields.
+func fieldValue(val reflect.Value, field *fieldPlan) reflect.Value {
+	if val.CanAddr() {
+		// accessing the field by its offset
+		// cf. https://stackoverflow.com/a/43918797
+		return reflect.NewAt(field.key.serviceType, unsafe.Add(unsafe.Pointer(val.UnsafeAddr()), field.offset)).Elem()
+	}
+	ret := val.Field(field.index)
+	if !ret.CanSet() {
+		ret = reflect.NewAt(field.key.serviceType, unsafe.Pointer(ret.UnsafeAddr())).Elem()
+	}
+	return ret
 }
 
-func (e *cacheActivator) activate(ctx resolveContext) (any, error) {
-	// check cache
-	if instance, ok := ctx.getCache(e, e.policy); ok {
-		return instance, nil
-	}
-	// activate new instance
-	instance, err := e.baseActivator.activate(ctx)
+func (e *fieldInjectionActivator) dependencies() ([]dependency, error) {
+	ret, err := e.baseActivator.dependencies()
 	if err != nil {
 		return nil, err
 	}
-	// store
-	ctx.setCache(e, instance, e.policy)
-	return instance, nil
+	base, ok := e.baseActivator.(typedActivator)
+	if !ok {
+		return ret, nil
+	}
+	t := base.instanceType()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// skip if instance is not a struct
+	if t.Kind() != reflect.Struct {
+		return ret, nil
+	}
+	plan := structPlanOf(t)
+	if plan.err != nil {
+		return nil, plan.err
+	}
+	for _, field := range plan.fields {
+		ret = append(ret, dependency{
+			key:       field.key,
+			argIndex:  -1,
+			fieldName: field.name,
+			optional:  field.info.optional,
+		})
+	}
+	return ret, nil
+}
+
+type cacheActivator struct {
+	baseActivator activator
+	// the registry where the activator is registered
+	owner  *registry
+	policy CachePolicy
+	// whether the cached instance is owned (and disposed) by the container
+	disposable bool
+}
+
+func (e *cacheActivator) activate(ctx resolveContext) (any, error) {
+	// get cached instance, or activate new instance and store it
+	return ctx.getOrCreateCache(e.owner, e, e.policy, e.disposable, e.baseActivator.activate)
+}
+
+func (e *cacheActivator) dependencies() ([]dependency, error) {
+	return e.baseActivator.dependencies()
 }
@@ -0,0 +1,261 @@
+package manioc
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+var (
+	errCacheCreationAborted = errors.New("instance creation was aborted")
+	errCacheWaitCycle       = errors.New(
+		"the instance is being created by another activation, which is waiting for this activation",
+	)
+)
+
+// cacheEntry holds a cached instance. While the instance is being activated,
+// `done` is open and other callers requesting the same entry wait for it.
+type cacheEntry struct {
+	done  chan struct{}
+	value any
+	err   error
+	// the frame of the activation which creates the instance
+	owner *resolveFrame
+}
+
+// cacheWaits records the entries which the activations are waiting for, across all caches,
+// to detect the activations waiting for each other.
+//
+//nolint:gochecknoglobals
+var cacheWaits = struct {
+	sync.Mutex
+	entries map[*resolveFrame]*cacheEntry
+}{
+	Mutex:   sync.Mutex{},
+	entries: make(map[*resolveFrame]*cacheEntry),
+}
+
+// Wait for the entry being created by another activation. If the owner of the entry is waiting,
+// directly or indirectly, for the waiter, it fails with errCacheWaitCycle instead of waiting forever.
+// The waiter is the frame of the activation requesting the entry, rather than its resolution,
+// since a resolution may run on multiple goroutines, e.g. the factory functions called within a constructor.
+func waitForEntry(waiter *resolveFrame, entry *cacheEntry) error {
+	select {
+	case <-entry.done:
+		return nil
+	default:
+	}
+	cacheWaits.Lock()
+	if isWaitingFor(entry.owner, waiter, make(map[*resolveFrame]struct{})) {
+		cacheWaits.Unlock()
+		return errCacheWaitCycle
+	}
+	cacheWaits.entries[waiter] = entry
+	cacheWaits.Unlock()
+	<-entry.done
+	cacheWaits.Lock()
+	delete(cacheWaits.entries, waiter)
+	cacheWaits.Unlock()
+	return nil
+}
+
+// Returns true if the activation of the owner cannot complete until the waiter completes, i.e. the waiter
+// is activated within the owner, or the owner is blocked on an entry whose owner is waiting for the waiter.
+// The owner is blocked if any activation within it is waiting for an entry. The caller must hold the lock.
+func isWaitingFor(owner *resolveFrame, waiter *resolveFrame, visited map[*resolveFrame]struct{}) bool {
+	if _, ok := visited[owner]; ok {
+		return false
+	}
+	visited[owner] = struct{}{}
+	if waiter.isWithin(owner) {
+		return true
+	}
+	for blocked, entry := range cacheWaits.entries {
+		if blocked.isWithin(owner) && isWaitingFor(entry.owner, waiter, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// createdInstance is an instance created in the cache.
+type createdInstance struct {
+	key   any
+	value any
+	// whether the instance is disposed by the cache
+	disposable bool
+}
+
+// instanceCache is a goroutine-safe instance cache.
+// Each entry is created exactly once, even if multiple goroutines request it at the same time.
+type instanceCache struct {
+	mu      sync.Mutex
+	entries map[any]*cacheEntry
+	// instances created in this cache, in creation order
+	created []createdInstance
+}
+
+func newInstanceCache() *instanceCache {
+	return &instanceCache{
+		entries: make(map[any]*cacheEntry),
+		created: make([]createdInstance, 0),
+	}
+}
+
+// Get the cached value for the key, or create it by calling `create`.
+// If `create` fails, the error is returned to all waiting callers and nothing is cached,
+// so that the next request will try to create the value again.
+// If `disposable` is true, the created value is disposed when the cache is disposed.
+// The frame identifies the activation of the caller, to detect the callers waiting for the entries
+// created by each other.
+func (c *instanceCache) getOrCreate(
+	key any,
+	frame *resolveFrame,
+	disposable bool,
+	create func() (any, error),
+) (any, error) {
+	for {
+		c.mu.Lock()
+		entry, ok := c.entries[key]
+		if !ok {
+			// this goroutine is responsible for the creation
+			entry = &cacheEntry{done: make(chan struct{}), value: nil, err: nil, owner: frame}
+			c.entries[key] = entry
+			c.mu.Unlock()
+			return c.create(key, entry, disposable, create)
+		}
+		c.mu.Unlock()
+		// wait for the creation by another goroutine
+		if err := waitForEntry(frame, entry); err != nil {
+			return nil, err
+		}
+		if entry.err == nil {
+			return entry.value, nil
+		}
+		// the creation has failed, so retry
+	}
+}
+
+func (c *instanceCache) create(key any, entry *cacheEntry, disposable bool, create func() (any, error)) (any, error) {
+	completed := false
+	defer func() {
+		if !completed {
+			// `create` has panicked; release waiting goroutines, then keep panicking
+			c.mu.Lock()
+			delete(c.entries, key)
+			entry.err = errCacheCreationAborted
+			c.mu.Unlock()
+			close(entry.done)
+		}
+	}()
+	value, err := create()
+	completed = true
+	c.mu.Lock()
+	if err != nil {
+		delete(c.entries, key)
+	} else {
+		c.created = append(c.created, createdInstance{key: key, value: value, disposable: disposable})
+	}
+	entry.value, entry.err = value, err
+	c.mu.Unlock()
+	close(entry.done)
+	return value, err
+}
+
+// Copy all completed entries into a new cache.
+// The copied instances are not disposed by the new cache.
+func (c *instanceCache) clone() *instanceCache {
+	ret := newInstanceCache()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		select {
+		case <-entry.done:
+			ret.entries[key] = entry
+		default:
+			// skip entries still being created
+		}
+	}
+	return ret
+}
+
+// Copy the completed entries for the keys into a new cache, replacing each key with the mapped one.
+// The copied instances are not disposed by the new cache.
+func (c *instanceCache) cloneWithKeys(keys map[any]any) *instanceCache {
+	ret := newInstanceCache()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		newKey, ok := keys[key]
+		if !ok {
+			continue
+		}
+		select {
+		case <-entry.done:
+			ret.entries[newKey] = entry
+		default:
+			// skip entries still being created
+		}
+	}
+	return ret
+}
+
+// Remove the entries for the keys, and forget the instances created for them without disposal.
+func (c *instanceCache) remove(keys []any) {
+	if len(keys) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := make(map[any]struct{}, len(keys))
+	for _, key := range keys {
+		delete(c.entries, key)
+		removed[key] = struct{}{}
+	}
+	created := make([]createdInstance, 0, len(c.created))
+	for _, instance := range c.created {
+		if _, ok := removed[instance.key]; !ok {
+			created = append(created, instance)
+		}
+	}
+	c.created = created
+}
+
+// Returns the instances created in this cache, in creation order.
+func (c *instanceCache) instances() []any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ret := make([]any, 0, len(c.created))
+	for _, instance := range c.created {
+		ret = append(ret, instance.value)
+	}
+	return ret
+}
+
+// Dispose all instances created in this cache in reverse creation order, and clear the cache.
+func (c *instanceCache) dispose() error {
+	c.mu.Lock()
+	created := c.created
+	c.entries = make(map[any]*cacheEntry)
+	c.created = make([]createdInstance, 0)
+	c.mu.Unlock()
+	errs := make([]error, 0)
+	disposed := make(map[any]struct{})
+	for i := len(created) - 1; i >= 0; i-- {
+		if !created[i].disposable || created[i].value == nil {
+			continue
+		}
+		instance := created[i].value
+		// the same instance may be cached with multiple keys
+		if reflect.TypeOf(instance).Kind() == reflect.Pointer {
+			if _, ok := disposed[instance]; ok {
+				continue
+			}
+			disposed[instance] = struct{}{}
+		}
+		if err := disposeInstance(instance); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return newAggregateError(errs)
+}
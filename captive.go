@@ -0,0 +1,109 @@
+package manioc
+
+import (
+	"fmt"
+)
+
+// Returns the rank of the lifetime of the cache policy; the larger, the longer-lived.
+func lifetimeOf(policy CachePolicy) int {
+	switch policy {
+	case GlobalCache:
+		return 2
+	case ScopedCache:
+		return 1
+	case NeverCache:
+		break
+	}
+	return 0
+}
+
+// Returns true if the instance with the `captive` policy must not be captured by
+// the instance with the `captor` policy.
+func isCaptive(mode CaptiveMode, captor CachePolicy, captive CachePolicy) bool {
+	switch mode {
+	case DefaultCaptiveMode:
+		return captor == GlobalCache && captive == ScopedCache
+	case StrictCaptiveMode:
+		return lifetimeOf(captor) > lifetimeOf(captive)
+	case IgnoreCaptiveMode:
+		break
+	}
+	return false
+}
+
+func newCaptiveCause(
+	captor registryKey,
+	captorPolicy CachePolicy,
+	captive registryKey,
+	captivePolicy CachePolicy,
+) error {
+	//nolint:goerr113
+	return fmt.Errorf("`%s` (%s) is captured by `%s` (%s)", captive, captivePolicy, captor, captorPolicy)
+}
+
+// Check that the registration being activated in this frame is not captured by its ancestors.
+func (f *resolveFrame) checkCaptive() error {
+	if f.context.captiveMode == IgnoreCaptiveMode {
+		return nil
+	}
+	for frame := f.parent; frame != nil && frame.parent != nil; frame = frame.parent {
+		if isCaptive(f.context.captiveMode, frame.policy, f.policy) {
+			return f.newError(ErrCaptiveDependency, newCaptiveCause(frame.key, frame.policy, f.key, f.policy))
+		}
+	}
+	return nil
+}
+
+// Validate that the dependencies of the registration are not captured by it.
+// NeverCache dependencies are traversed, since their dependencies are captured as well,
+// while the dependencies of cached ones are validated on their own.
+func (v *validator) checkCaptive(key registryKey, entry *registration) {
+	policy := entry.info.CachePolicy
+	if v.captiveMode == IgnoreCaptiveMode || policy == NeverCache {
+		return
+	}
+	v.path = []registryKey{key}
+	defer func() { v.path = v.path[:0] }()
+	v.walkCaptive(key, policy, entry, map[*registration]struct{}{entry: {}})
+}
+
+func (v *validator) walkCaptive(
+	captor registryKey,
+	policy CachePolicy,
+	entry *registration,
+	visited map[*registration]struct{},
+) {
+	// the errors have already been reported by visit
+	deps, _ := entry.dependenciesFor(registryKey{serviceType: entry.info.ServiceType, serviceKey: entry.info.ServiceKey})
+	for _, dep := range deps {
+		key, targets := v.captiveTargets(dep.key)
+		for _, target := range targets {
+			if isCaptive(v.captiveMode, policy, target.info.CachePolicy) {
+				v.report(key, ErrCaptiveDependency, newCaptiveCause(captor, policy, key, target.info.CachePolicy))
+				continue
+			}
+			if _, ok := visited[target]; ok || target.info.CachePolicy != NeverCache {
+				continue
+			}
+			visited[target] = struct{}{}
+			v.path = append(v.path, key)
+			v.walkCaptive(captor, policy, target, visited)
+			v.path = v.path[:len(v.path)-1]
+		}
+	}
+}
+
+// Returns the registrations which are activated immediately to resolve the key, with their key.
+// The registrations resolved lazily via Lazy[T] or func() (T, error) are not included.
+func (v *validator) captiveTargets(key registryKey) (registryKey, []*registration) {
+	target := v.registry.lookupTarget(key)
+	switch target.kind {
+	case targetRegistered, targetAll:
+		return target.key, target.entries
+	case targetOptional:
+		return v.captiveTargets(target.key)
+	case targetDeferred, targetContext, targetNotRegistered:
+		break
+	}
+	return key, nil
+}
@@ -2,20 +2,128 @@ package manioc
 
 type defaultContainer struct {
 	defaultScope
+	modules   *moduleRegistry
+	lifecycle *lifecycle
 }
 
 func (c *defaultContainer) getRegisterContext() registerContext {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.context == nil {
+		return nil
+	}
 	return c.context
 }
 
-func newDefaultContainer() *defaultContainer {
-	return &defaultContainer{
+func (c *defaultContainer) getModuleRegistry() *moduleRegistry {
+	return c.modules
+}
+
+func (c *defaultContainer) getLifecycle() *lifecycle {
+	return c.lifecycle
+}
+
+// Close the container and dispose the instances it owns,
+// including the instances cached with GlobalCache policy.
+// The child scopes opened with InheritCacheMode or SyncCacheMode, and the child containers are also closed.
+func (c *defaultContainer) Close() error {
+	return c.closeScope()
+}
+
+func (c *defaultContainer) closeScope() error {
+	c.mu.RLock()
+	context := c.context
+	c.mu.RUnlock()
+	errs := make([]error, 0)
+	if err := c.defaultScope.closeScope(); err != nil {
+		errs = append(errs, err)
+	}
+	if context != nil {
+		if err := context.globalCache.dispose(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return newAggregateError(errs)
+}
+
+func (c *defaultContainer) NewChild() Container {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ret := &defaultContainer{
 		defaultScope: defaultScope{
-			context:     newDefaultContext(),
+			context:     nil,
 			childScopes: make([]Scope, 0),
+			detach:      nil,
+			ownsCache:   true,
 		},
+		modules:   newModuleRegistry(),
+		lifecycle: newLifecycle(),
+	}
+	if c.context == nil {
+		// the parent container has been closed, so the new container is also closed
+		return ret
 	}
+	ret.context = &defaultContext{
+		parent:         c.context,
+		container:      nil,
+		cacheOwner:     nil,
+		scope:          ret,
+		registry:       newRegistry(c.context.registry),
+		instances:      newRegistry(nil),
+		globalCache:    newInstanceCache(),
+		scopedCache:    newInstanceCache(),
+		closed:         0,
+		captiveMode:    c.context.captiveMode,
+		conflictPolicy: c.context.conflictPolicy,
+	}
+	ret.context.container = ret.context
+	ret.context.cacheOwner = ret.context
+	// register child container into parent, to close it with the parent
+	ret.detach = c.addChild(ret)
+	return ret
+}
+
+func (c *defaultContainer) Clone() Container {
+	return c.clone(false)
 }
 
-//nolint:gochecknoglobals
-var globalContainer Container = newDefaultContainer()
+func (c *defaultContainer) snapshot() Container {
+	return c.clone(true)
+}
+
+func (c *defaultContainer) clone(withCache bool) *defaultContainer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ret := &defaultContainer{
+		defaultScope: defaultScope{
+			context:     nil,
+			childScopes: make([]Scope, 0),
+			detach:      nil,
+			ownsCache:   true,
+		},
+		modules:   c.modules.clone(),
+		lifecycle: c.lifecycle.clone(),
+	}
+	if c.context == nil {
+		// the container has been closed, so the clone is also closed
+		return ret
+	}
+	ret.context = c.context.clone(withCache)
+	ret.context.scope = ret
+	return ret
+}
+
+func newDefaultContainer(options *containerOptions) *defaultContainer {
+	ret := &defaultContainer{
+		defaultScope: defaultScope{
+			context:     newDefaultContext(options),
+			childScopes: make([]Scope, 0),
+			detach:      nil,
+			ownsCache:   true,
+		},
+		modules:   newModuleRegistry(),
+		lifecycle: newLifecycle(),
+	}
+	ret.context.scope = ret
+	return ret
+}
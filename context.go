@@ -1,107 +1,306 @@
 package manioc
 
 import (
-	"errors"
-	"reflect"
+	"context"
+	"sync/atomic"
 )
 
 type defaultContext struct {
-	registry    map[registryKey][]activator
-	globalCache map[any]any
-	scopedCache map[any]any
+	// the context of the parent container, or nil
+	parent *defaultContext
+	// the context of the container which this context belongs to, i.e. itself for containers
+	container *defaultContext
+	// the context which owns the scoped cache, i.e. the one of the parent for the scopes opened
+	// with SyncCacheMode, or itself otherwise
+	cacheOwner *defaultContext
+	// the scope which this context belongs to
+	scope    Scope
+	registry *registry
+	// the instances registered only for the scope
+	instances   *registry
+	globalCache *instanceCache
+	scopedCache *instanceCache
+	// set to non-zero when the scope is closed
+	closed      int32
+	captiveMode CaptiveMode
+	// the conflict policy of the registrations without WithConflictPolicy
+	conflictPolicy ConflictPolicy
 }
 
-func newDefaultContext() *defaultContext {
-	return &defaultContext{
-		registry:    make(map[registryKey][]activator),
-		globalCache: make(map[any]any),
-		scopedCache: make(map[any]any),
+func newDefaultContext(options *containerOptions) *defaultContext {
+	ret := &defaultContext{
+		parent:         nil,
+		container:      nil,
+		cacheOwner:     nil,
+		scope:          nil,
+		registry:       newRegistry(nil),
+		instances:      newRegistry(nil),
+		globalCache:    newInstanceCache(),
+		scopedCache:    newInstanceCache(),
+		closed:         0,
+		captiveMode:    options.captiveMode,
+		conflictPolicy: options.conflictPolicy,
 	}
+	ret.container = ret
+	ret.cacheOwner = ret
+	return ret
 }
 
-func (c *defaultContext) register(key registryKey, entry activator) error {
-	if _, ok := c.registry[key]; !ok {
-		c.registry[key] = make([]activator, 0)
+func (c *defaultContext) close() {
+	atomic.StoreInt32(&c.closed, 1)
+}
+
+func (c *defaultContext) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) != 0
+}
+
+func (c *defaultContext) newRegistration(
+	key registryKey,
+	base activator,
+	policy CachePolicy,
+	onActivated []func(instance any) error,
+) *registration {
+	// instances given by the user are neither initialized nor disposed by the container
+	_, external := base.(*instanceActivator)
+	// install the generated activator, which falls back to field injection activator
+	activator := newGeneratedActivator(base)
+	// install initializing activator
+	activator = &initializingActivator{baseActivator: activator, initializer: !external, hooks: onActivated}
+	// install decorating activator
+	activator = &decoratingActivator{baseActivator: activator, key: key, owner: c.registry}
+	// install cache activator
+	cache := &cacheActivator{
+		baseActivator: activator,
+		owner:         c.registry,
+		policy:        policy,
+		disposable:    !external,
+	}
+	// install observing activator, which also observes the instances served from the caches
+	activator = &observingActivator{baseActivator: cache, key: key, owner: c.registry}
+	ret := newRegistration(key, base, activator, c.registry, policy)
+	ret.cacheKey = cache
+	ret.onActivated = onActivated
+	return ret
+}
+
+func (c *defaultContext) register(key registryKey, base activator, options *registerOptions) error {
+	entry := c.newRegistration(key, base, options.policy, options.onActivated)
+	return c.registry.addWithPolicy(key, entry, c.conflictPolicyOf(options))
+}
+
+// Returns the conflict policy of the registration, or the default of the container if not specified.
+func (c *defaultContext) conflictPolicyOf(options *registerOptions) ConflictPolicy {
+	if options.conflictPolicy == defaultConflictPolicy {
+		return c.conflictPolicy
+	}
+	return options.conflictPolicy
+}
+
+func (c *defaultContext) registerScoped(key registryKey, base activator, options *registerOptions) error {
+	if c.isClosed() {
+		return newScopeClosedError(key.serviceType, key.serviceKey)
+	}
+	// the instances registered for the parent scopes are not considered as conflicts
+	return c.instances.addWithPolicy(key, c.newRegistration(key, base, ScopedCache, nil), c.conflictPolicyOf(options))
+}
+
+func (c *defaultContext) declareScoped(key registryKey) {
+	// the declaration is never activated, so the activator is not installed
+	base := &declaredActivator{serviceType: key.serviceType}
+	c.registry.declare(key, newRegistration(key, base, base, c.registry, ScopedCache))
+}
+
+// Returns the cache keys of the instances registered for the scope.
+func (c *defaultContext) scopedInstanceKeys() []any {
+	var ret []any
+	for _, entry := range c.instances.localEntries() {
+		ret = append(ret, entry.cacheKey)
+	}
+	return ret
+}
+
+// Returns the registrations for the key.
+// The instances registered for the scope take precedence over the registrations in the container.
+func (c *defaultContext) lookup(key registryKey) []*registration {
+	if entries := c.instances.get(key); len(entries) > 0 {
+		return entries
 	}
-	c.registry[key] = append(c.registry[key], entry)
+	return c.registry.get(key)
+}
+
+func (c *defaultContext) decorate(key registryKey, d *decorator) error {
+	c.registry.addDecorator(key, d)
 	return nil
 }
 
-func (c *defaultContext) setCache(key any, value any, policy CachePolicy) {
+func (c *defaultContext) observe(key registryKey, fn func(instance any)) error {
+	c.registry.addObserver(key, fn)
+	return nil
+}
+
+// Returns the context of the container where the registration is made,
+// i.e. this context or one of its ancestors.
+func (c *defaultContext) ownerOf(owner *registry) *defaultContext {
+	ctx := c
+	for ctx.registry != owner && ctx.parent != nil {
+		ctx = ctx.parent
+	}
+	return ctx
+}
+
+func (c *defaultContext) getOrCreateCache(
+	owner *registry,
+	key any,
+	policy CachePolicy,
+	disposable bool,
+	create func(ctx resolveContext) (any, error),
+) (any, error) {
+	return c.root().getOrCreateCache(owner, key, policy, disposable, create)
+}
+
+// Get the cached instance from the cache for the policy, or create and cache a new one.
+func (c *defaultContext) getOrCreateInstance(
+	owner *registry,
+	key any,
+	frame *resolveFrame,
+	policy CachePolicy,
+	disposable bool,
+	create func() (any, error),
+) (any, error) {
 	switch policy {
 	case GlobalCache:
-		c.globalCache[key] = value
+		// the instance is cached in the container where the registration is made
+		return c.ownerOf(owner).globalCache.getOrCreate(key, frame, disposable, create)
 	case ScopedCache:
-		c.scopedCache[key] = value
+		return c.scopedCache.getOrCreate(key, frame, disposable, create)
 	case NeverCache:
 		break
 	}
+	return create()
 }
 
-func (c *defaultContext) getCache(key any, policy CachePolicy) (any, bool) {
+// Returns the context which owns the cache for the policy, or nil for NeverCache.
+func (c *defaultContext) cacheOwnerOf(policy CachePolicy) *defaultContext {
 	switch policy {
 	case GlobalCache:
-		if value, ok := c.globalCache[key]; ok {
-			return value, true
-		}
+		return c.container
 	case ScopedCache:
-		if value, ok := c.scopedCache[key]; ok {
-			return value, true
-		}
+		return c.cacheOwner
 	case NeverCache:
 		break
 	}
-	return nil, false
+	return nil
 }
 
-func (c *defaultContext) resolveAll(key registryKey) (any, error) {
-	tkey := registryKey{serviceType: key.serviceType.Elem(), serviceKey: key.serviceKey}
-	entries, ok := c.registry[tkey]
-	num := len(entries)
-	if !ok || num == 0 {
-		return nil, errors.New("no registration found")
-	}
-	// resolve all
-	instances := reflect.MakeSlice(key.serviceType, num, num)
-	for i, entry := range entries {
-		instance, err := entry.activate(c)
-		if err != nil {
-			return nil, err
-		}
-		instances.Index(i).Set(reflect.ValueOf(instance))
+// Returns the frame to start a new dependency chain.
+func (c *defaultContext) root() *resolveFrame {
+	return c.rootWithContext(context.Background())
+}
+
+func (c *defaultContext) withContext(ctx context.Context) resolveContext {
+	return c.rootWithContext(ctx)
+}
+
+// Returns the frame to start a new dependency chain with the context.Context.
+func (c *defaultContext) rootWithContext(ctx context.Context) *resolveFrame {
+	ret := &resolveFrame{
+		context:    c,
+		parent:     nil,
+		activation: nil,
+		key:        registryKey{serviceType: nil, serviceKey: nil},
+		policy:     NeverCache,
+		ctx:        ctx,
+		binding:    c,
+		finished:   0,
 	}
-	return instances.Interface(), nil
+	ret.activation = ret
+	return ret
 }
 
 func (c *defaultContext) resolve(key registryKey) (any, error) {
-	// look up entry with key
-	entries, ok := c.registry[key]
-	if !ok {
-		// if service type is []T, look up with T
-		if key.serviceType.Kind() == reflect.Slice {
-			return c.resolveAll(key)
-		}
-		return nil, errors.New("no registration found")
-	}
-	// resolve one
-	if len(entries) == 0 {
-		return nil, errors.New("no registration found")
-	}
-	if len(entries) > 1 {
-		return nil, errors.New("multiple registration found")
-	}
-	return entries[0].activate(c)
+	return c.root().resolve(key)
+}
+
+func (c *defaultContext) resolveOptional(key registryKey) (any, bool, error) {
+	return c.root().resolveOptional(key)
+}
+
+func (c *defaultContext) newError(err error, cause error) error {
+	return c.root().newError(err, cause)
 }
 
 func (c *defaultContext) isRegistered(key registryKey) bool {
-	entries, ok := c.registry[key]
-	return ok && len(entries) > 0
+	return len(c.registry.get(key)) > 0
 }
 
 func (c *defaultContext) unregister(key registryKey) bool {
-	if ret, ok := c.registry[key]; ok && len(ret) > 0 {
-		c.registry[key] = make([]activator, 0)
-		return true
+	return c.registry.remove(key)
+}
+
+func (c *defaultContext) registrations() []Registration {
+	keys, entries := c.registry.all()
+	ret := make([]Registration, 0, len(keys))
+	for _, key := range keys {
+		for _, entry := range entries[key] {
+			ret = append(ret, entry.info)
+		}
+	}
+	return ret
+}
+
+// Returns a new context with the copies of the registrations, the decorators and the observers of this context.
+// The registrations are rebuilt for the new context, so that their instances are cached in the new context.
+// If withCache is true, the cached instances of this context are also copied for the rebuilt registrations.
+func (c *defaultContext) clone(withCache bool) *defaultContext {
+	ret := &defaultContext{
+		parent:         c.parent,
+		container:      nil,
+		cacheOwner:     nil,
+		scope:          nil,
+		registry:       newRegistry(c.registry.parent),
+		instances:      newRegistry(nil),
+		globalCache:    newInstanceCache(),
+		scopedCache:    newInstanceCache(),
+		closed:         0,
+		captiveMode:    c.captiveMode,
+		conflictPolicy: c.conflictPolicy,
+	}
+	ret.container = ret
+	ret.cacheOwner = ret
+	// the cache keys of the registrations of this context, mapped to the ones of the rebuilt registrations
+	cacheKeys := make(map[any]any)
+	copyRegistry := func(dst *registry, src *registry) {
+		entries, decorators, observers, declared := src.local()
+		for key, list := range entries {
+			for _, entry := range list {
+				copied := ret.newRegistration(key, entry.base, entry.info.CachePolicy, entry.onActivated)
+				// keep the description, including the site of the registration
+				copied.info = entry.info
+				dst.add(key, copied)
+				cacheKeys[entry.cacheKey] = copied.cacheKey
+			}
+		}
+		for key, list := range decorators {
+			for _, d := range list {
+				dst.addDecorator(key, d)
+			}
+		}
+		for key, list := range observers {
+			for _, fn := range list {
+				dst.addObserver(key, fn)
+			}
+		}
+		for key, entry := range declared {
+			copied := newRegistration(key, entry.base, entry.base, dst, ScopedCache)
+			copied.info = entry.info
+			dst.declare(key, copied)
+		}
+	}
+	copyRegistry(ret.registry, c.registry)
+	copyRegistry(ret.instances, c.instances)
+	if withCache {
+		ret.globalCache = c.globalCache.cloneWithKeys(cacheKeys)
+		ret.scopedCache = c.scopedCache.cloneWithKeys(cacheKeys)
 	}
-	return false
+	return ret
 }
@@ -0,0 +1,109 @@
+package manioc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+type decorator struct {
+	plan *functionPlan
+}
+
+func newDecorator[T any, TDecorator any](fn TDecorator, argumentKeys map[int]any) (*decorator, error) {
+	// check type parameters
+	tRet := typeof[T]()
+	tFn := typeof[TDecorator]()
+	if tFn.Kind() != reflect.Func {
+		panic(errors.New("the type of TDecorator should be a function"))
+	}
+	// in[0] should be T
+	if tFn.NumIn() < 1 || tFn.In(0) != tRet {
+		panic(fmt.Errorf(
+			"the first argument of TDecorator=`%s` should be T=`%s`",
+			nameof[TDecorator](),
+			nameof[T](),
+		))
+	}
+	// out[0] should be assignable to T, and out[1] should be an error if exists
+	if tFn.NumOut() < 1 || tFn.NumOut() > 2 || !tFn.Out(0).AssignableTo(tRet) ||
+		(tFn.NumOut() == 2 && tFn.Out(1) != typeof[error]()) {
+		panic(fmt.Errorf(
+			"the return values of TDecorator=`%s` should be either T=`%s` or (T, error)",
+			nameof[TDecorator](),
+			nameof[T](),
+		))
+	}
+	// check fn value
+	if !reflect.ValueOf(fn).IsValid() || reflect.ValueOf(fn).IsNil() {
+		return nil, errors.New("decorator is invalid or nil")
+	}
+	// check argument keys; the first argument is the decorated instance
+	for index := range argumentKeys {
+		if index < 1 || index >= tFn.NumIn() {
+			return nil, fmt.Errorf("argument index `%d` is out of range for TDecorator=`%s`", index, nameof[TDecorator]())
+		}
+	}
+	return &decorator{plan: newFunctionPlan(fn, argumentKeys)}, nil
+}
+
+func (d *decorator) decorate(ctx resolveContext, instance any) (any, error) {
+	return d.plan.call(ctx, ErrDecoratorFailed, reflect.ValueOf(instance))
+}
+
+func (d *decorator) dependencies() []dependency {
+	// the first argument is the decorated instance
+	return d.plan.dependencies(1)
+}
+
+// decoratingActivator applies the decorators registered for the key to the activated instance.
+type decoratingActivator struct {
+	baseActivator activator
+	key           registryKey
+	// the registry where the activator is registered
+	owner *registry
+}
+
+func (e *decoratingActivator) activate(ctx resolveContext) (any, error) {
+	instance, err := e.baseActivator.activate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range e.owner.getDecorators(e.key) {
+		instance, err = d.decorate(ctx, instance)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return instance, nil
+}
+
+func (e *decoratingActivator) dependencies() ([]dependency, error) {
+	// the dependencies of the decorators are not included,
+	// since the decorators are looked up from the registry at activation.
+	return e.baseActivator.dependencies()
+}
+
+// Decorate registers a decorator for the service type T, which wraps the resolved instances of T.
+// The decorator should be a function whose first argument is T, and returns T or (T, error).
+// The other arguments of the decorator are resolved from the container,
+// and WithArgumentKey can be used to specify their keys.
+//
+// The decorator applies to all registrations of T with the key specified by WithRegisterKey,
+// including the registrations made after the call of Decorate. If multiple decorators are registered,
+// they are applied in the order of registration, i.e. the first one wraps the original instance.
+// The decorated instance is cached according to the cache policy of the registration,
+// so the instances cached before the call of Decorate are not decorated.
+func Decorate[T any, TDecorator any](decorator TDecorator, opts ...RegisterOption) error {
+	options := mergeRegisterOptions(opts)
+	d, err := newDecorator[T](decorator, options.argumentKeys)
+	if err != nil {
+		return err
+	}
+	ctx := options.container.getRegisterContext()
+	if ctx == nil {
+		return newScopeClosedError(typeof[T](), options.key)
+	}
+	key := registryKey{serviceType: typeof[T](), serviceKey: options.key}
+	return ctx.decorate(key, d)
+}
@@ -0,0 +1,28 @@
+package manioc
+
+import (
+	"fmt"
+	"io"
+)
+
+// Disposable is an interface for instances that release their resources
+// when the scope or container that owns them is closed.
+type Disposable interface {
+	Dispose() error
+}
+
+func disposeInstance(instance any) error {
+	var err error
+	switch v := instance.(type) {
+	case Disposable:
+		err = v.Dispose()
+	case io.Closer:
+		err = v.Close()
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to dispose `%T`: %w", instance, err)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package manioc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrNotRegistered indicates that no registration is found for the requested service.
+	ErrNotRegistered = errors.New("no registration found")
+	// ErrAmbiguous indicates that multiple registrations are found for the requested service,
+	// so the container cannot determine which one to use.
+	ErrAmbiguous = errors.New("multiple registrations found")
+	// ErrCircularDependency indicates that the requested service depends on itself.
+	ErrCircularDependency = errors.New("circular dependency detected")
+	// ErrCaptiveDependency indicates that the requested service is captured by a longer-lived service,
+	// e.g. a ScopedCache service is injected into a GlobalCache service. See CaptiveMode for details.
+	ErrCaptiveDependency = errors.New("captive dependency detected")
+	// ErrCanceled indicates that the context.Context of the resolution is canceled or its deadline is exceeded.
+	// The error of the context can be retrieved with errors.Is, e.g. errors.Is(err, context.Canceled).
+	ErrCanceled = errors.New("resolution canceled")
+	// ErrScopeClosed indicates that the resolution is requested within a closed scope.
+	ErrScopeClosed = errors.New("the scope has been closed")
+	// ErrConstructorFailed indicates that the constructor of the requested service returned an error.
+	// The error returned by the constructor can be retrieved with errors.Unwrap, errors.Is or errors.As.
+	ErrConstructorFailed = errors.New("constructor failed")
+	// ErrDecoratorFailed indicates that a decorator of the requested service returned an error.
+	// The error returned by the decorator can be retrieved with errors.Unwrap, errors.Is or errors.As.
+	ErrDecoratorFailed = errors.New("decorator failed")
+	// ErrInitializerFailed indicates that the initialization of the requested service returned an error,
+	// i.e. Init of Initializer, or the function given by WithOnActivated.
+	// The error returned by the initialization can be retrieved with errors.Unwrap, errors.Is or errors.As.
+	ErrInitializerFailed = errors.New("initializer failed")
+	// ErrDuplicateRegistration indicates that the service has already been registered with the same key,
+	// and the registration is made with FailOnDuplicate policy. See ConflictPolicy for details.
+	ErrDuplicateRegistration = errors.New("duplicate registration")
+)
+
+// Dependency identifies a service registered in a container.
+type Dependency struct {
+	ServiceType reflect.Type
+	ServiceKey  any
+}
+
+func (d Dependency) String() string {
+	return registryKey{serviceType: d.ServiceType, serviceKey: d.ServiceKey}.String()
+}
+
+// ResolveError is the error returned when the resolution fails.
+// Use errors.Is to check the reason of the failure, e.g. errors.Is(err, ErrNotRegistered),
+// and errors.As to retrieve the details.
+type ResolveError struct {
+	// One of ErrNotRegistered, ErrAmbiguous, ErrCircularDependency, ErrCaptiveDependency,
+	// ErrCanceled, ErrScopeClosed, ErrConstructorFailed, ErrDecoratorFailed or ErrInitializerFailed.
+	Err error
+	// The service which failed to be resolved. It is nil for direct resolutions.
+	ServiceType reflect.Type
+	ServiceKey  any
+	// The chain of parent resolutions that led to the failure, from the outermost one.
+	Path []Dependency
+	// The underlying error, such as the one returned by the constructor.
+	Cause error
+}
+
+func (e *ResolveError) Error() string {
+	msg := e.Err.Error()
+	if e.ServiceType != nil {
+		parts := make([]string, 0, len(e.Path)+1)
+		for _, dep := range e.Path {
+			parts = append(parts, dep.String())
+		}
+		parts = append(parts, Dependency{ServiceType: e.ServiceType, ServiceKey: e.ServiceKey}.String())
+		msg += ": " + strings.Join(parts, " -> ")
+	}
+	if e.Cause != nil {
+		msg += fmt.Sprintf(": %v", e.Cause)
+	}
+	return msg
+}
+
+func (e *ResolveError) Is(target error) bool {
+	return e.Err == target //nolint:errorlint,goerr113
+}
+
+func (e *ResolveError) Unwrap() error {
+	return e.Cause
+}
+
+func siteOrUnknown(site string) string {
+	if site == "" {
+		return "unknown location"
+	}
+	return site
+}
+
+func newDuplicateRegistrationError(key registryKey, existing []*registration, entry *registration) error {
+	sites := make([]string, 0, len(existing))
+	for _, e := range existing {
+		sites = append(sites, siteOrUnknown(e.info.Site))
+	}
+	return fmt.Errorf(
+		"%w: `%s` registered at %s has already been registered at %s",
+		ErrDuplicateRegistration,
+		key,
+		siteOrUnknown(entry.info.Site),
+		strings.Join(sites, ", "),
+	)
+}
+
+// Returns the error for the request made on a closed scope or container.
+// The service type is nil if the request is not for a specific service.
+func newScopeClosedError(serviceType reflect.Type, serviceKey any) error {
+	return &ResolveError{
+		Err:         ErrScopeClosed,
+		ServiceType: serviceType,
+		ServiceKey:  serviceKey,
+		Path:        []Dependency{},
+		Cause:       nil,
+	}
+}
+
+// AggregateError is an error that consists of multiple errors,
+// such as the errors returned while closing a scope.
+// errors.Is and errors.As match any of the errors.
+type AggregateError struct {
+	Errors []error
+}
+
+func newAggregateError(errs []error) error {
+	// flatten nested aggregate errors
+	flattened := make([]error, 0, len(errs))
+	for _, err := range errs {
+		var aggregated *AggregateError
+		if errors.As(err, &aggregated) {
+			flattened = append(flattened, aggregated.Errors...)
+		} else {
+			flattened = append(flattened, err)
+		}
+	}
+	if len(flattened) == 0 {
+		return nil
+	}
+	return &AggregateError{Errors: flattened}
+}
+
+func (e *AggregateError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d error(s) occurred: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *AggregateError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *AggregateError) As(target any) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package manioc
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ActivateFunc activates an instance without reflection, including the field injection.
+// It is usually generated by `manioc gen`, and used instead of the reflection-based activation
+// for the registrations of the type or the constructor it is generated for.
+type ActivateFunc func(a *Activation) (any, error)
+
+// Activation provides the dependencies of the instance being activated to ActivateFunc.
+type Activation struct {
+	ctx resolveContext
+	// service keys for the arguments of the constructor, by argument index
+	argumentKeys map[int]any
+}
+
+// the key of generatedActivators for constructors, since functions are not comparable
+type constructorKey uintptr
+
+//nolint:gochecknoglobals
+var generatedActivators = struct {
+	sync.RWMutex
+	entries map[any]ActivateFunc
+}{
+	RWMutex: sync.RWMutex{},
+	entries: make(map[any]ActivateFunc),
+}
+
+func setGeneratedActivator(key any, fn ActivateFunc) {
+	if fn == nil {
+		panic(errors.New("the ActivateFunc should not be nil"))
+	}
+	generatedActivators.Lock()
+	defer generatedActivators.Unlock()
+	generatedActivators.entries[key] = fn
+}
+
+// UseGeneratedType specifies the ActivateFunc for the implementation type T, which is used by
+// Register and its variants. T is the type of the activated instances, i.e. the pointer type
+// if TImplementation is a struct type registered for an interface type.
+// It should be called before the first activation of the registrations, e.g. in the init functions.
+func UseGeneratedType[T any](fn ActivateFunc) {
+	setGeneratedActivator(typeof[T](), fn)
+}
+
+// UseGeneratedConstructor specifies the ActivateFunc for the constructor, which is used by
+// RegisterConstructor and its variants. The constructor should be a top-level function,
+// since the function values are identified by their code. It panics for the function literals
+// and the method values, whose values share the same code.
+// It should be called before the first activation of the registrations, e.g. in the init functions.
+func UseGeneratedConstructor(ctor any, fn ActivateFunc) {
+	if reflect.TypeOf(ctor) == nil || reflect.TypeOf(ctor).Kind() != reflect.Func {
+		panic(errors.New("the constructor should be a function"))
+	}
+	if !isTopLevelFunc(reflect.ValueOf(ctor)) {
+		panic(errors.New("the constructor should be a top-level function, not a function literal or a method value"))
+	}
+	setGeneratedActivator(constructorKey(reflect.ValueOf(ctor).Pointer()), fn)
+}
+
+// Returns true if the function value refers to a top-level function, e.g. `pkg.NewFoo` or `pkg.NewFoo[...]`,
+// rather than a function literal, e.g. `pkg.init.func1` or `pkg.NewFoo[...].func1`,
+// or a method, e.g. `pkg.(*Foo).New-fm`.
+func isTopLevelFunc(fn reflect.Value) bool {
+	if fn.IsNil() {
+		return false
+	}
+	f := runtime.FuncForPC(fn.Pointer())
+	if f == nil {
+		return false
+	}
+	// remove the type arguments of the generic functions, but keep what follows,
+	// e.g. `pkg.NewFoo[...].func1` is a function literal
+	var b strings.Builder
+	depth := 0
+	for _, r := range f.Name() {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	// remove the path of the package, which may contain dots
+	name := b.String()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.Count(name, ".") == 1
+}
+
+// Returns the activator which uses the generated ActivateFunc for the base activator if available,
+// or the field injection with reflection otherwise.
+func newGeneratedActivator(base activator) activator {
+	fallback := &fieldInjectionActivator{baseActivator: base}
+	var key any
+	var argumentKeys map[int]any
+	switch base := base.(type) {
+	case *implementationActivator:
+		key = base.implementationType
+	case *constructorActivator:
+		key = constructorKey(reflect.ValueOf(base.constructor).Pointer())
+		argumentKeys = base.argumentKeys
+	default:
+		return fallback
+	}
+	return &generatedActivator{
+		key:          key,
+		fallback:     fallback,
+		argumentKeys: argumentKeys,
+		once:         sync.Once{},
+		fn:           nil,
+	}
+}
+
+// generatedActivator activates the instances with ActivateFunc, including the field injection.
+// The ActivateFunc is looked up on the first activation rather than on registration,
+// so that it does not depend on the order of the init functions.
+type generatedActivator struct {
+	key any
+	// the field injection with reflection, which is used if ActivateFunc is not available
+	fallback     activator
+	argumentKeys map[int]any
+	once         sync.Once
+	fn           ActivateFunc
+}
+
+func (e *generatedActivator) activate(ctx resolveContext) (any, error) {
+	e.once.Do(func() {
+		generatedActivators.RLock()
+		defer generatedActivators.RUnlock()
+		e.fn = generatedActivators.entries[e.key]
+	})
+	if e.fn == nil {
+		return e.fallback.activate(ctx)
+	}
+	return e.fn(&Activation{ctx: ctx, argumentKeys: e.argumentKeys})
+}
+
+func (e *generatedActivator) dependencies() ([]dependency, error) {
+	// the same as the reflection-based activation
+	return e.fallback.dependencies()
+}
+
+func resolveAs[T any](a *Activation, key any) (T, error) {
+	var ret T
+	instance, err := a.ctx.resolve(registryKey{serviceType: typeof[T](), serviceKey: key})
+	if err != nil {
+		return ret, err
+	}
+	// instance may be nil, e.g. nil interfaces returned by constructors
+	ret, _ = instance.(T)
+	return ret, nil
+}
+
+// Argument resolves the argument of the constructor at the given index (starting from 0),
+// with the service key specified by WithArgumentKey.
+func Argument[T any](a *Activation, index int) (T, error) {
+	return resolveAs[T](a, a.argumentKeys[index])
+}
+
+// Field resolves the field tagged with `manioc:"inject"`, with the service key given by the tag.
+func Field[T any](a *Activation, key any) (T, error) {
+	return resolveAs[T](a, key)
+}
+
+// OptionalField resolves the field tagged with `manioc:"inject,optional"`, with the service key given by the tag.
+// It returns false if the dependency is not registered, and then the field should be left as it is.
+func OptionalField[T any](a *Activation, key any) (T, bool, error) {
+	var ret T
+	instance, ok, err := a.ctx.resolveOptional(registryKey{serviceType: typeof[T](), serviceKey: key})
+	if err != nil || !ok {
+		return ret, false, err
+	}
+	ret, _ = instance.(T)
+	return ret, true, nil
+}
+
+// Fail wraps the error returned by the constructor with ErrConstructorFailed.
+func (a *Activation) Fail(err error) error {
+	return a.ctx.newError(ErrConstructorFailed, err)
+}
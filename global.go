@@ -0,0 +1,63 @@
+package manioc
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// globalContainerHolder wraps the global container, since atomic.Value requires the same concrete type.
+type globalContainerHolder struct {
+	container Container
+}
+
+// the container used when neither WithContainer nor WithScope is specified
+//
+//nolint:gochecknoglobals
+var globalContainer = func() *atomic.Value {
+	ret := &atomic.Value{}
+	ret.Store(globalContainerHolder{
+		container: newDefaultContainer(&containerOptions{captiveMode: DefaultCaptiveMode, conflictPolicy: Append}),
+	})
+	return ret
+}()
+
+// GlobalContainer returns the container used when neither WithContainer nor WithScope is specified.
+func GlobalContainer() Container {
+	holder, _ := globalContainer.Load().(globalContainerHolder)
+	return holder.container
+}
+
+// SetGlobalContainer replaces the global container, and returns the replaced one.
+// The replaced container is not closed, so that it can be restored later.
+// It is safe to call this function concurrently with the registrations and the resolutions,
+// which use either the replaced container or the new one.
+func SetGlobalContainer(container Container) Container {
+	if container == nil {
+		panic(errors.New("the global container should not be nil"))
+	}
+	holder, _ := globalContainer.Swap(globalContainerHolder{container: container}).(globalContainerHolder)
+	return holder.container
+}
+
+// GlobalSnapshot is a copy of the global container captured by SnapshotGlobal.
+type GlobalSnapshot struct {
+	container Container
+}
+
+// SnapshotGlobal captures the registrations, the decorators, the installed modules, the lifecycle hooks
+// and the cached instances of the global container. The global container is not changed.
+// The instances being created at the time are not captured.
+func SnapshotGlobal() *GlobalSnapshot {
+	return &GlobalSnapshot{container: GlobalContainer().snapshot()}
+}
+
+// RestoreGlobal replaces the global container with a copy of the snapshot, and returns the replaced one.
+// The restored container shares the cached instances with the container where the snapshot was taken,
+// but does not dispose them. Since the copy is made on each call, the snapshot can be restored multiple times.
+// Note that closing the container where the snapshot was taken disposes the shared instances.
+func RestoreGlobal(snapshot *GlobalSnapshot) Container {
+	if snapshot == nil {
+		panic(errors.New("the snapshot should not be nil"))
+	}
+	return SetGlobalContainer(snapshot.container.snapshot())
+}
@@ -0,0 +1,234 @@
+package manioc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EdgeStatus is an enumerated type that specifies whether a dependency can be resolved.
+type EdgeStatus string
+
+const (
+	// The dependency is resolved with the registrations the edge points to.
+	EdgeResolved EdgeStatus = "resolved"
+	// No registration is found for the dependency.
+	// It is not an error for optional dependencies.
+	EdgeUnresolved EdgeStatus = "unresolved"
+	// Multiple registrations are found for the dependency.
+	EdgeAmbiguous EdgeStatus = "ambiguous"
+)
+
+// DependencyGraph is the graph of the registrations in a container and their dependencies.
+type DependencyGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a registration in the dependency graph,
+// or an instance declared to be registered for the scopes with DeclareScopedInstance.
+type GraphNode struct {
+	ID             string `json:"id"`
+	ServiceType    string `json:"serviceType"`
+	ServiceKey     string `json:"serviceKey,omitempty"`
+	CachePolicy    string `json:"cachePolicy"`
+	Kind           string `json:"kind"`
+	Implementation string `json:"implementation"`
+	Site           string `json:"site,omitempty"`
+}
+
+// GraphEdge is a dependency of a registration in the dependency graph.
+type GraphEdge struct {
+	// The ID of the node that requires the dependency.
+	From string `json:"from"`
+	// The IDs of the nodes that the dependency is resolved with.
+	// It is empty if the dependency is unresolved.
+	To          []string `json:"to"`
+	ServiceType string   `json:"serviceType"`
+	ServiceKey  string   `json:"serviceKey,omitempty"`
+	// The index of the constructor argument, or -1 for field injection.
+	ArgIndex int `json:"argIndex"`
+	// The name of the injected field, or empty for constructor injection.
+	FieldName string     `json:"fieldName,omitempty"`
+	Optional  bool       `json:"optional"`
+	Status    EdgeStatus `json:"status"`
+}
+
+// Graph builds the dependency graph of the registrations in the container.
+// The dependencies are collected from the constructor arguments, the tagged fields and the decorators,
+// without activating any instances.
+func Graph(container Container) (*DependencyGraph, error) {
+	ctx := container.getRegisterContext()
+	if ctx == nil {
+		return nil, newScopeClosedError(nil, nil)
+	}
+	return ctx.graph()
+}
+
+func (c *defaultContext) graph() (*DependencyGraph, error) {
+	keys, entries := c.registry.all()
+	g := &DependencyGraph{
+		Nodes: make([]GraphNode, 0),
+		Edges: make([]GraphEdge, 0),
+	}
+	ids := make(map[*registration]string)
+	addNode := func(key registryKey, entry *registration) {
+		id := fmt.Sprintf("n%d", len(g.Nodes))
+		ids[entry] = id
+		g.Nodes = append(g.Nodes, GraphNode{
+			ID:             id,
+			ServiceType:    key.serviceType.String(),
+			ServiceKey:     formatServiceKey(key.serviceKey),
+			CachePolicy:    entry.info.CachePolicy.String(),
+			Kind:           entry.info.Kind.String(),
+			Implementation: fmt.Sprint(entry.info.Implementation),
+			Site:           entry.info.Site,
+		})
+	}
+	for _, key := range keys {
+		for _, entry := range entries[key] {
+			addNode(key, entry)
+		}
+	}
+	// the instances declared to be registered for the scopes, unless they are registered in the container
+	declaredKeys, declared := c.registry.allDeclared()
+	for _, key := range declaredKeys {
+		if len(entries[key]) == 0 {
+			addNode(key, declared[key])
+		}
+	}
+	for _, key := range keys {
+		for _, entry := range entries[key] {
+			deps, err := entry.dependenciesFor(key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid registration for `%s`: %w", key, err)
+			}
+			for _, dep := range deps {
+				targets, status, optional := c.graphTargets(dep.key, dep.optional)
+				to := make([]string, 0, len(targets))
+				for _, target := range targets {
+					to = append(to, ids[target])
+				}
+				g.Edges = append(g.Edges, GraphEdge{
+					From:        ids[entry],
+					To:          to,
+					ServiceType: dep.key.serviceType.String(),
+					ServiceKey:  formatServiceKey(dep.key.serviceKey),
+					ArgIndex:    dep.argIndex,
+					FieldName:   dep.fieldName,
+					Optional:    optional,
+					Status:      status,
+				})
+			}
+		}
+	}
+	return g, nil
+}
+
+// Look up the registrations the key is resolved with, in the same way as resolveFrame.resolve.
+func (c *defaultContext) graphTargets(key registryKey, optional bool) ([]*registration, EdgeStatus, bool) {
+	target := c.registry.lookupTarget(key)
+	switch target.kind {
+	case targetRegistered:
+		if len(target.entries) > 1 {
+			return target.entries, EdgeAmbiguous, optional
+		}
+		return target.entries, EdgeResolved, optional
+	case targetAll:
+		if len(target.entries) > 0 {
+			return target.entries, EdgeResolved, optional
+		}
+	case targetDeferred:
+		return c.graphTargets(target.key, optional)
+	case targetOptional:
+		return c.graphTargets(target.key, true)
+	case targetContext:
+		return nil, EdgeResolved, optional
+	case targetNotRegistered:
+		break
+	}
+	return nil, EdgeUnresolved, optional
+}
+
+func formatServiceKey(key any) string {
+	if key == nil {
+		return ""
+	}
+	return fmt.Sprint(key)
+}
+
+// WriteJSON writes the graph in JSON format.
+func (g *DependencyGraph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(g)
+}
+
+// WriteDOT writes the graph in the DOT language of Graphviz.
+// Unresolved dependencies are drawn as red dashed nodes, ambiguous dependencies as orange edges,
+// and missing optional dependencies as gray dashed edges.
+func (g *DependencyGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph manioc {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, node := range g.Nodes {
+		label := []string{node.ServiceType}
+		if node.ServiceKey != "" {
+			label = append(label, "key="+node.ServiceKey)
+		}
+		label = append(label, node.Kind+": "+node.Implementation, node.CachePolicy)
+		fmt.Fprintf(&b, "  %s [label=%s];\n", quoteDOT(node.ID), quoteDOT(strings.Join(label, "\n")))
+	}
+	for i, edge := range g.Edges {
+		label := edge.FieldName
+		if edge.ArgIndex >= 0 {
+			label = fmt.Sprintf("arg %d", edge.ArgIndex)
+		}
+		attrs := ""
+		switch {
+		case edge.Status == EdgeAmbiguous:
+			label += " (ambiguous)"
+			attrs = ", color=orange, fontcolor=orange"
+		case edge.Status == EdgeUnresolved && edge.Optional:
+			label += " (optional)"
+			attrs = ", style=dashed, color=gray, fontcolor=gray"
+		case edge.Status == EdgeUnresolved:
+			label += " (unresolved)"
+			attrs = ", color=red, fontcolor=red"
+		}
+		if len(edge.To) == 0 {
+			// draw a placeholder node for the missing dependency,
+			// or for the dependency provided by the container itself, such as context.Context
+			id := fmt.Sprintf("missing%d", i)
+			name := edge.ServiceType
+			if edge.ServiceKey != "" {
+				name += "\nkey=" + edge.ServiceKey
+			}
+			switch {
+			case edge.Status == EdgeResolved:
+				fmt.Fprintf(&b, "  %s [label=%s, style=dotted];\n", quoteDOT(id), quoteDOT(name))
+			case edge.Optional:
+				fmt.Fprintf(&b, "  %s [label=%s, style=dashed, color=gray, fontcolor=gray];\n", quoteDOT(id), quoteDOT(name))
+			default:
+				fmt.Fprintf(&b, "  %s [label=%s, style=dashed, color=red, fontcolor=red];\n", quoteDOT(id), quoteDOT(name))
+			}
+			fmt.Fprintf(&b, "  %s -> %s [label=%s%s];\n", quoteDOT(edge.From), quoteDOT(id), quoteDOT(label), attrs)
+			continue
+		}
+		for _, to := range edge.To {
+			fmt.Fprintf(&b, "  %s -> %s [label=%s%s];\n", quoteDOT(edge.From), quoteDOT(to), quoteDOT(label), attrs)
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func quoteDOT(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -1,11 +1,18 @@
 package manioc
 
-func NewContainer() Container {
-	return newDefaultContainer()
+func NewContainer(opts ...ContainerOption) Container {
+	options := &containerOptions{
+		captiveMode:    DefaultCaptiveMode,
+		conflictPolicy: Append,
+	}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	return newDefaultContainer(options)
 }
 
 func OpenScope(opts ...OpenScopeOption) (Scope, func()) {
-	return globalContainer.OpenScope(opts...)
+	return GlobalContainer().OpenScope(opts...)
 }
 
 func RegisterSingleton[TInterface any, TImplementation any](opts ...RegisterOption) error {
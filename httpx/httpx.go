@@ -0,0 +1,93 @@
+// Package httpx provides the integration of manioc with net/http.
+package httpx
+
+import (
+	"net/http"
+
+	"github.com/fuzmish/manioc"
+)
+
+type middlewareOptions struct {
+	cacheMode manioc.ScopeCacheMode
+}
+
+type MiddlewareOption interface {
+	apply(*middlewareOptions)
+}
+
+// WithCacheMode
+
+type withCacheMode struct{ cacheMode manioc.ScopeCacheMode }
+
+func (opt *withCacheMode) apply(options *middlewareOptions) {
+	options.cacheMode = opt.cacheMode
+}
+
+// WithCacheMode specifies the ScopeCacheMode of the scopes opened per request.
+// The default is manioc.DefaultCacheMode. Note that with manioc.SyncCacheMode, the ScopedCache instances
+// are shared with the parent and thus across the requests, so they should not depend on the request.
+func WithCacheMode(cacheMode manioc.ScopeCacheMode) MiddlewareOption {
+	return &withCacheMode{cacheMode: cacheMode}
+}
+
+// Middleware returns a middleware which opens a child scope of the parent per request.
+// The *http.Request and the http.ResponseWriter are registered into the scope with
+// manioc.RegisterScopedInstance, so that the services resolved within the scope can inject them.
+// If the parent is a container, they are declared with manioc.DeclareScopedInstance,
+// so that manioc.Validate called after this function treats them as resolved.
+// The context of the request carries the scope, which can be retrieved with ScopeFromRequest.
+// The scope is closed after the handler returns.
+func Middleware(parent manioc.Scope, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	// merge options
+	options := &middlewareOptions{
+		cacheMode: manioc.DefaultCacheMode,
+	}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	if ctr, ok := parent.(manioc.Container); ok {
+		// the declarations fail only if the container has been closed, where the requests fail anyway
+		_ = manioc.DeclareScopedInstance[*http.Request](manioc.WithContainer(ctr))
+		_ = manioc.DeclareScopedInstance[http.ResponseWriter](manioc.WithContainer(ctr))
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope, cleanup := parent.OpenScope(manioc.WithCacheMode(options.cacheMode))
+			defer cleanup()
+			r = r.WithContext(manioc.ContextWithScope(r.Context(), scope))
+			if err := manioc.RegisterScopedInstance(scope, r); err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			if err := manioc.RegisterScopedInstance(scope, w); err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ScopeFromRequest returns the scope opened for the request by Middleware.
+func ScopeFromRequest(r *http.Request) (manioc.Scope, bool) {
+	return manioc.ScopeFromContext(r.Context())
+}
+
+// Resolve resolves T within the scope of the request, with the context of the request.
+// If the WithScope option is not specified, the scope opened by Middleware is used.
+func Resolve[T any](r *http.Request, opts ...manioc.ResolveOption) (T, error) {
+	return manioc.ResolveContext[T](r.Context(), opts...)
+}
+
+// Handler returns a http.Handler which resolves the handler T within the scope of the request,
+// and delegates the request to it. If the resolution fails, it responds with 500 Internal Server Error.
+func Handler[T http.Handler](opts ...manioc.ResolveOption) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, err := Resolve[T](r, opts...)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,71 @@
+package manioc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Initializer is implemented by the services which need to be set up after they are activated.
+// Init is called once the constructor and the field injection are done, before the instance is
+// decorated and cached. If Init returns an error, the resolution fails with ErrInitializerFailed.
+// The instances registered with RegisterInstance or RegisterScopedInstance are not initialized,
+// since they are given by the user.
+type Initializer interface {
+	Init() error
+}
+
+// initializingActivator runs the initialization of the activated instance.
+type initializingActivator struct {
+	baseActivator activator
+	// whether Initializer is honored
+	initializer bool
+	// the hooks given by WithOnActivated, in the order of the options
+	hooks []func(instance any) error
+}
+
+func (e *initializingActivator) activate(ctx resolveContext) (any, error) {
+	instance, err := e.baseActivator.activate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if initializer, ok := instance.(Initializer); ok && e.initializer {
+		if err := initializer.Init(); err != nil {
+			return nil, ctx.newError(ErrInitializerFailed, err)
+		}
+	}
+	for _, hook := range e.hooks {
+		if err := hook(instance); err != nil {
+			return nil, ctx.newError(ErrInitializerFailed, err)
+		}
+	}
+	return instance, nil
+}
+
+func (e *initializingActivator) dependencies() ([]dependency, error) {
+	return e.baseActivator.dependencies()
+}
+
+// WithOnActivated
+
+type withOnActivated struct{ hook func(instance any) error }
+
+func (opt *withOnActivated) apply(options *registerOptions) {
+	options.onActivated = append(options.onActivated, opt.hook)
+}
+
+// WithOnActivated specifies the function called with the activated instance, in the same way as Initializer.
+// It is called after Init if the instance implements Initializer. If multiple functions are specified,
+// they are called in the order of the options. The resolution fails if the activated instance is not
+// of type T, e.g. the constructor returns an implementation other than T.
+func WithOnActivated[T any](fn func(instance T) error) RegisterOption {
+	if fn == nil {
+		panic(errors.New("the function for WithOnActivated should not be nil"))
+	}
+	return &withOnActivated{hook: func(instance any) error {
+		value, ok := instance.(T)
+		if !ok {
+			return fmt.Errorf("the activated instance of type `%T` is not T=`%s`", instance, nameof[T]())
+		}
+		return fn(value)
+	}}
+}
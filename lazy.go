@@ -0,0 +1,132 @@
+package manioc
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// Lazy is a wrapper of a dependency which is resolved on the first call of Get.
+// A Lazy can be injected via constructor or field injection, in place of the dependency itself:
+//
+//	func NewMyService(foo manioc.Lazy[IFooService]) *MyService { ... }
+//
+// The dependency is resolved within the scope where the Lazy is injected, or within the container
+// if it is injected into a GlobalCache service, since the service may outlive the scope.
+// Since the resolution is deferred, a Lazy can be used to break circular dependencies,
+// as long as Get is not called while the dependency chain is being activated, e.g. within a constructor.
+type Lazy[T any] struct {
+	state *lazyState
+}
+
+type lazyState struct {
+	mu       sync.Mutex
+	resolve  func() (any, error)
+	resolved bool
+	value    any
+}
+
+// Get resolves the dependency on the first call, and returns the same instance after that.
+// If the resolution fails, the error is returned and the next call will try again.
+func (l Lazy[T]) Get() (T, error) {
+	if l.state == nil {
+		return *new(T), errors.New("the Lazy is not injected by the container")
+	}
+	l.state.mu.Lock()
+	defer l.state.mu.Unlock()
+	if !l.state.resolved {
+		value, err := l.state.resolve()
+		if err != nil {
+			return *new(T), err
+		}
+		l.state.value, l.state.resolved = value, true
+	}
+	//nolint:forcetypeassert
+	return l.state.value.(T), nil
+}
+
+// MustGet is a variant of Get that panics if the resolution fails.
+func (l Lazy[T]) MustGet() T {
+	ret, err := l.Get()
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+func (l *Lazy[T]) bindLazy(resolve func() (any, error)) {
+	l.state = &lazyState{mu: sync.Mutex{}, resolve: resolve, resolved: false, value: nil}
+}
+
+func (l *Lazy[T]) lazyElemType() reflect.Type {
+	return typeof[T]()
+}
+
+// lazyBinder is implemented by the pointer to Lazy[T].
+type lazyBinder interface {
+	bindLazy(resolve func() (any, error))
+	lazyElemType() reflect.Type
+}
+
+// Returns the element key if the service type is Lazy[T].
+func lazyElemKey(key registryKey) (registryKey, bool) {
+	if key.serviceType.Kind() != reflect.Struct {
+		return registryKey{}, false
+	}
+	binder, ok := reflect.New(key.serviceType).Interface().(lazyBinder)
+	if !ok {
+		return registryKey{}, false
+	}
+	return registryKey{serviceType: binder.lazyElemType(), serviceKey: key.serviceKey}, true
+}
+
+// Returns the element key if the service type is a factory function `func() (T, error)`.
+func factoryElemKey(key registryKey) (registryKey, bool) {
+	t := key.serviceType
+	if t.Kind() != reflect.Func || t.IsVariadic() || t.NumIn() != 0 || t.NumOut() != 2 || t.Out(1) != typeof[error]() {
+		return registryKey{}, false
+	}
+	return registryKey{serviceType: t.Out(0), serviceKey: key.serviceKey}, true
+}
+
+// Resolve the key lazily, where this frame is the one of the Lazy or the factory function.
+// If it is called while its ancestors are still being activated, e.g. within the constructor,
+// the resolution continues the dependency chain of the nearest one, so that the circular dependencies
+// are detected instead of waiting for the instances being created. Otherwise, the resolution starts
+// a new dependency chain within the bound context.
+func (f *resolveFrame) deferredResolve(key registryKey) (any, error) {
+	if f.binding.isClosed() {
+		return nil, newScopeClosedError(key.serviceType, key.serviceKey)
+	}
+	for frame := f.parent; frame != nil && frame.parent != nil; frame = frame.parent {
+		if !frame.isFinished() {
+			return frame.resolve(key)
+		}
+	}
+	return f.binding.resolve(key)
+}
+
+func (f *resolveFrame) resolveLazy(key registryKey, elemKey registryKey) (any, error) {
+	ret := reflect.New(key.serviceType)
+	//nolint:forcetypeassert
+	ret.Interface().(lazyBinder).bindLazy(func() (any, error) {
+		return f.deferredResolve(elemKey)
+	})
+	return ret.Elem().Interface(), nil
+}
+
+func (f *resolveFrame) resolveFactory(key registryKey, elemKey registryKey) (any, error) {
+	tFn := key.serviceType
+	fn := reflect.MakeFunc(tFn, func([]reflect.Value) []reflect.Value {
+		instance := reflect.New(tFn.Out(0)).Elem()
+		err := reflect.New(tFn.Out(1)).Elem()
+		value, e := f.deferredResolve(elemKey)
+		if e != nil {
+			err.Set(reflect.ValueOf(e))
+		} else if value != nil {
+			instance.Set(reflect.ValueOf(value))
+		}
+		return []reflect.Value{instance, err}
+	})
+	return fn.Interface(), nil
+}
@@ -0,0 +1,290 @@
+package manioc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrAlreadyStarted indicates that the container has already been started with Start.
+var ErrAlreadyStarted = errors.New("the container has already been started")
+
+// Starter is an interface for services that start running with the container, such as servers.
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
+// Stopper is an interface for services that stop running with the container.
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// Hook is a pair of functions called when the container starts and stops.
+// Either of OnStart and OnStop may be nil.
+type Hook struct {
+	// The name of the hook, which is used in error messages.
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// lifecycleParticipant is a service or a hook which is started and stopped with the container.
+type lifecycleParticipant interface {
+	start(ctx context.Context) error
+	stop(ctx context.Context) error
+	name() string
+}
+
+type serviceParticipant struct {
+	instance any
+}
+
+func (p *serviceParticipant) start(ctx context.Context) error {
+	if starter, ok := p.instance.(Starter); ok {
+		return starter.Start(ctx)
+	}
+	return nil
+}
+
+func (p *serviceParticipant) stop(ctx context.Context) error {
+	if stopper, ok := p.instance.(Stopper); ok {
+		return stopper.Stop(ctx)
+	}
+	return nil
+}
+
+func (p *serviceParticipant) name() string {
+	return fmt.Sprintf("%T", p.instance)
+}
+
+type hookParticipant struct {
+	hook Hook
+}
+
+func (p *hookParticipant) start(ctx context.Context) error {
+	if p.hook.OnStart != nil {
+		return p.hook.OnStart(ctx)
+	}
+	return nil
+}
+
+func (p *hookParticipant) stop(ctx context.Context) error {
+	if p.hook.OnStop != nil {
+		return p.hook.OnStop(ctx)
+	}
+	return nil
+}
+
+func (p *hookParticipant) name() string {
+	if p.hook.Name == "" {
+		return "hook"
+	}
+	return p.hook.Name
+}
+
+// lifecycle records the hooks and the started participants of a container.
+type lifecycle struct {
+	// mu guards hooks, which may be registered by constructors while starting
+	mu    sync.Mutex
+	hooks []Hook
+	// phaseMu serializes the start and stop phases, and guards the fields below
+	phaseMu sync.Mutex
+	running bool
+	// the participants which have been started, in start order
+	started []lifecycleParticipant
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		mu:      sync.Mutex{},
+		hooks:   make([]Hook, 0),
+		phaseMu: sync.Mutex{},
+		running: false,
+		started: make([]lifecycleParticipant, 0),
+	}
+}
+
+// Returns a new lifecycle with the copies of the hooks, which has not been started.
+func (l *lifecycle) clone() *lifecycle {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ret := newLifecycle()
+	ret.hooks = append(ret.hooks, l.hooks...)
+	return ret
+}
+
+// Stop the started participants in reverse start order. The caller should hold phaseMu.
+func (l *lifecycle) stopAll(ctx context.Context) []error {
+	errs := make([]error, 0)
+	for i := len(l.started) - 1; i >= 0; i-- {
+		p := l.started[i]
+		if err := p.stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop `%s`: %w", p.name(), err))
+		}
+	}
+	l.started = make([]lifecycleParticipant, 0)
+	l.running = false
+	return errs
+}
+
+// Returns true if the instances of the registration may implement Starter or Stopper.
+func mayImplementLifecycle(entry *registration) bool {
+	t := entry.info.Implementation
+	if entry.info.Kind == ConstructorRegistration {
+		t = t.Out(0)
+	}
+	if t == nil || t.Kind() == reflect.Interface {
+		// the dynamic type is unknown until activation
+		return true
+	}
+	return t.Implements(typeof[Starter]()) || t.Implements(typeof[Stopper]())
+}
+
+// Activate the GlobalCache registrations of this container which may implement Starter or Stopper,
+// and returns all instances cached with GlobalCache policy, in creation order.
+// The context.Context is used to cancel the activation; the one injected into the singletons is detached
+// from it, since the singletons outlive the start.
+func (c *defaultContext) activateSingletons(ctx context.Context) ([]any, error) {
+	keys, entries := c.registry.all()
+	for _, key := range keys {
+		for _, entry := range entries[key] {
+			// the inherited registrations are started with their own container
+			if entry.owner != c.registry || entry.info.CachePolicy != GlobalCache || !mayImplementLifecycle(entry) {
+				continue
+			}
+			frame, err := c.rootWithContext(ctx).push(key)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := entry.activate(frame); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c.globalCache.instances(), nil
+}
+
+// RegisterHook registers a hook which is called when the container starts and stops.
+// The hooks are started after the services in the order of registration, and stopped before the services.
+// Only the WithContainer option is effective.
+func RegisterHook(hook Hook, opts ...RegisterOption) error {
+	options := mergeRegisterOptions(opts)
+	lc := options.container.getLifecycle()
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.hooks = append(lc.hooks, hook)
+	return nil
+}
+
+// Start the services and the hooks of the container.
+//
+// The GlobalCache registrations of the container whose instances may implement Starter or Stopper are
+// activated, and then the GlobalCache instances implementing Starter are started in dependency order,
+// i.e. an instance is started after its dependencies. After that, the hooks registered with RegisterHook
+// are started in the order of registration. If any of them fails, the ones already started are stopped
+// in reverse order, and the errors are returned as an *AggregateError.
+func Start(ctx context.Context, ctr Container, opts ...LifecycleOption) error {
+	options := mergeLifecycleOptions(opts)
+	lc := ctr.getLifecycle()
+	lc.phaseMu.Lock()
+	defer lc.phaseMu.Unlock()
+	if lc.running {
+		return ErrAlreadyStarted
+	}
+	startCtx, cancel := withTimeout(ctx, options.startTimeout)
+	defer cancel()
+	// collect participants
+	registerCtx := ctr.getRegisterContext()
+	if registerCtx == nil {
+		return newScopeClosedError(nil, nil)
+	}
+	instances, err := registerCtx.activateSingletons(startCtx)
+	if err != nil {
+		return err
+	}
+	participants := make([]lifecycleParticipant, 0)
+	seen := make(map[any]struct{})
+	for _, instance := range instances {
+		switch instance.(type) {
+		case Starter, Stopper:
+		default:
+			continue
+		}
+		// the same instance may be cached with multiple keys
+		if reflect.TypeOf(instance).Kind() == reflect.Pointer {
+			if _, ok := seen[instance]; ok {
+				continue
+			}
+			seen[instance] = struct{}{}
+		}
+		participants = append(participants, &serviceParticipant{instance: instance})
+	}
+	lc.mu.Lock()
+	for _, hook := range lc.hooks {
+		participants = append(participants, &hookParticipant{hook: hook})
+	}
+	lc.mu.Unlock()
+	// start
+	lc.running = true
+	for _, p := range participants {
+		err := startCtx.Err()
+		if err == nil {
+			err = p.start(startCtx)
+		}
+		if err != nil {
+			errs := []error{fmt.Errorf("failed to start `%s`: %w", p.name(), err)}
+			// roll back
+			stopCtx, cancel := withTimeout(context.Background(), options.stopTimeout)
+			defer cancel()
+			errs = append(errs, lc.stopAll(stopCtx)...)
+			return newAggregateError(errs)
+		}
+		lc.started = append(lc.started, p)
+	}
+	return nil
+}
+
+// Stop the services and the hooks of the container started with Start, in reverse start order.
+// All of them are stopped even if some of them fail, and the errors are returned as an *AggregateError.
+// It does nothing if the container is not started.
+func Stop(ctx context.Context, ctr Container, opts ...LifecycleOption) error {
+	options := mergeLifecycleOptions(opts)
+	lc := ctr.getLifecycle()
+	lc.phaseMu.Lock()
+	defer lc.phaseMu.Unlock()
+	if !lc.running {
+		return nil
+	}
+	stopCtx, cancel := withTimeout(ctx, options.stopTimeout)
+	defer cancel()
+	return newAggregateError(lc.stopAll(stopCtx))
+}
+
+// Run starts the container, blocks until an interrupt or termination signal arrives or
+// the context is done, and then stops the container. The stop phase is not bound to the context,
+// so use the WithStopTimeout option to limit its duration. The signals are also handled during the start
+// phase: the start is canceled, and the services and the hooks already started are stopped.
+func Run(ctx context.Context, ctr Container, opts ...LifecycleOption) error {
+	// handle the signals before starting, not to be killed without stopping during a slow start
+	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := Start(signalCtx, ctr, opts...); err != nil {
+		return err
+	}
+	<-signalCtx.Done()
+	stop()
+	return Stop(context.Background(), ctr, opts...)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fuzmish/manioc/linter/manioctypechecker"
+	"golang.org/x/tools/go/packages"
+)
+
+const usage = `usage: manioc gen [-o file] [packages]
+
+gen generates the reflection-free activation for the Register and RegisterConstructor calls
+in the packages, and writes it to the file in each package directory.
+`
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "gen" { //nolint:gomnd
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2) //nolint:gomnd
+	}
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	output := flags.String("o", "manioc_gen.go", "the name of the generated file")
+	_ = flags.Parse(os.Args[2:])
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	if err := generate(*output, patterns); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(output string, patterns []string) error {
+	config := &packages.Config{
+		//nolint:exhaustivestruct
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		// exclude the files generated previously, which may be outdated
+		BuildFlags: []string{"-tags=" + manioctypechecker.GeneratedBuildTag},
+	}
+	pkgs, err := packages.Load(config, patterns...)
+	if err != nil {
+		return err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return errors.New("failed to load the packages")
+	}
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) == 0 {
+			continue
+		}
+		src, err := manioctypechecker.Generate(pkg.Types, pkg.TypesInfo, pkg.Syntax)
+		if err != nil {
+			return fmt.Errorf("failed to generate for %s: %w", pkg.PkgPath, err)
+		}
+		if src == nil {
+			continue
+		}
+		path := filepath.Join(filepath.Dir(pkg.GoFiles[0]), output)
+		//nolint:gomnd,gosec
+		if err := os.WriteFile(path, src, 0o644); err != nil {
+			return err
+		}
+		fmt.Println(path)
+	}
+	return nil
+}
@@ -0,0 +1,413 @@
+package manioctypechecker
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/types"
+	pathpkg "path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GeneratedBuildTag is the build tag to exclude the generated files while generating them.
+const GeneratedBuildTag = "maniocgen"
+
+const maniocPath = "github.com/fuzmish/manioc"
+
+// the identifiers used within the generated functions, which should not be shadowed
+//
+//nolint:gochecknoglobals
+var reservedNames = regexp.MustCompile(`^(a|ret|err|ok|arg[0-9]+|field[0-9]+)$`)
+
+type generator struct {
+	pkg  *types.Package
+	info *types.Info
+	// the import names by the package paths
+	imports map[string]string
+	// the generated init statements by the target types or constructors
+	entries map[string]string
+}
+
+// Generate generates the Go source file for the package, which provides the reflection-free activation
+// for the types and constructors registered with the Register and RegisterConstructor calls in the files.
+// The registrations which cannot be generated, e.g. the constructors given by function literals or
+// the types with inaccessible fields to inject, are skipped, so they are activated with reflection.
+// It returns nil if there is nothing to generate.
+func Generate(pkg *types.Package, info *types.Info, files []*ast.File) ([]byte, error) {
+	gen := &generator{
+		pkg:     pkg,
+		info:    info,
+		imports: map[string]string{maniocPath: "manioc"},
+		entries: make(map[string]string),
+	}
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if call, ok := node.(*ast.CallExpr); ok {
+				gen.visitCall(call)
+			}
+			return true
+		})
+	}
+	if len(gen.entries) == 0 {
+		return nil, nil
+	}
+	return gen.emit()
+}
+
+func (g *generator) visitCall(call *ast.CallExpr) {
+	var fun ast.Expr
+	var indices []ast.Expr
+	switch expr := call.Fun.(type) {
+	case *ast.IndexListExpr:
+		fun = expr.X
+		indices = expr.Indices
+	case *ast.IndexExpr:
+		fun = expr.X
+		indices = []ast.Expr{expr.Index}
+	default:
+		return
+	}
+	selector, ok := fun.(*ast.SelectorExpr)
+	if !ok || !isManiocPackage(g.info, selector.X) {
+		return
+	}
+	switch selector.Sel.Name {
+	case "Register",
+		"RegisterSingleton",
+		"RegisterScoped",
+		"RegisterTransient":
+		if len(indices) != 2 { //nolint:gomnd
+			return
+		}
+		tImpl, message := validateRegisterTypeParameters(g.info.TypeOf(indices[0]), g.info.TypeOf(indices[1]))
+		if message != "" {
+			return
+		}
+		g.generateType(tImpl)
+	case "RegisterConstructor",
+		"RegisterSingletonConstructor",
+		"RegisterScopedConstructor",
+		"RegisterTransientConstructor":
+		if len(call.Args) < 1 {
+			return
+		}
+		signature, message := validateRegisterConstructorTypeParameters(
+			g.info.TypeOf(indices[0]),
+			g.info.TypeOf(call.Args[0]),
+		)
+		if message != "" {
+			return
+		}
+		g.generateConstructor(call.Args[0], signature)
+	}
+}
+
+// Returns the top-level function referred by the expression, or nil.
+func (g *generator) lookupFunc(expr ast.Expr) *types.Func {
+	var ident *ast.Ident
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		ident = expr
+	case *ast.SelectorExpr:
+		// the function of other packages
+		if _, ok := g.info.Uses[identOf(expr.X)].(*types.PkgName); !ok {
+			return nil
+		}
+		ident = expr.Sel
+	default:
+		return nil
+	}
+	fn, ok := g.info.Uses[ident].(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return nil
+	}
+	//nolint:forcetypeassert
+	signature := fn.Type().(*types.Signature)
+	if signature.Recv() != nil || signature.TypeParams().Len() > 0 {
+		return nil
+	}
+	return fn
+}
+
+func identOf(expr ast.Expr) *ast.Ident {
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident
+	}
+	return nil
+}
+
+// Returns the reference to the object in the generated file, or false if it is not accessible.
+func (g *generator) objectString(obj types.Object) (string, bool) {
+	if obj.Pkg() == g.pkg {
+		return obj.Name(), !reservedNames.MatchString(obj.Name())
+	}
+	if !obj.Exported() {
+		return "", false
+	}
+	return g.importName(obj.Pkg()) + "." + obj.Name(), true
+}
+
+// Returns the name to refer the package in the generated file.
+func (g *generator) importName(pkg *types.Package) string {
+	if name, ok := g.imports[pkg.Path()]; ok {
+		return name
+	}
+	used := make(map[string]bool, len(g.imports))
+	for _, name := range g.imports {
+		used[name] = true
+	}
+	name := pkg.Name()
+	for i := 2; used[name] || g.pkg.Scope().Lookup(name) != nil || reservedNames.MatchString(name); i++ {
+		name = fmt.Sprintf("%s%d", pkg.Name(), i)
+	}
+	g.imports[pkg.Path()] = name
+	return name
+}
+
+// Returns true if the type can be referred in the generated file.
+//
+//nolint:cyclop
+func (g *generator) isAccessible(t types.Type) bool {
+	switch t := t.(type) {
+	case *types.Basic:
+		return t.Kind() != types.UnsafePointer
+	case *types.Named:
+		if t.Obj().Pkg() != nil {
+			if _, ok := g.objectString(t.Obj()); !ok {
+				return false
+			}
+		}
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			if !g.isAccessible(t.TypeArgs().At(i)) {
+				return false
+			}
+		}
+		return true
+	case *types.Pointer:
+		return g.isAccessible(t.Elem())
+	case *types.Slice:
+		return g.isAccessible(t.Elem())
+	case *types.Array:
+		return g.isAccessible(t.Elem())
+	case *types.Chan:
+		return g.isAccessible(t.Elem())
+	case *types.Map:
+		return g.isAccessible(t.Key()) && g.isAccessible(t.Elem())
+	case *types.Signature:
+		return g.isAccessibleTuple(t.Params()) && g.isAccessibleTuple(t.Results())
+	case *types.Interface:
+		return t.Empty()
+	default:
+		// the struct types and type parameters are not supported
+		return false
+	}
+}
+
+func (g *generator) isAccessibleTuple(tuple *types.Tuple) bool {
+	for i := 0; i < tuple.Len(); i++ {
+		if !g.isAccessible(tuple.At(i).Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(pkg *types.Package) string {
+		if pkg == g.pkg {
+			return ""
+		}
+		return g.importName(pkg)
+	})
+}
+
+type injectedField struct {
+	name     string
+	t        types.Type
+	key      *string
+	optional bool
+}
+
+// Returns the fields to inject to the struct type, or false if they cannot be generated.
+// It follows the parsing of the struct tags in the manioc package.
+func (g *generator) injectedFields(t types.Type) ([]injectedField, bool) {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil, true
+	}
+	ret := make([]injectedField, 0)
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		info := injectedField{name: field.Name(), t: field.Type(), key: nil, optional: false}
+		inject := false
+		for _, part := range strings.Split(reflect.StructTag(st.Tag(i)).Get("manioc"), ",") {
+			switch {
+			case part == "":
+				continue
+			case part == "inject":
+				inject = true
+			case part == "optional":
+				info.optional = true
+			case strings.HasPrefix(part, "key="):
+				if len(part) > len("key=") {
+					key := part[len("key="):]
+					info.key = &key
+				}
+			default:
+				// the unknown tag is reported on activation
+				return nil, false
+			}
+		}
+		if !inject {
+			continue
+		}
+		if (!field.Exported() && field.Pkg() != g.pkg) || !g.isAccessible(field.Type()) {
+			return nil, false
+		}
+		ret = append(ret, info)
+	}
+	return ret, true
+}
+
+// Writes the statements to inject the fields into `ret`.
+func (g *generator) writeFields(buf *bytes.Buffer, fields []injectedField) {
+	for i, field := range fields {
+		key := "nil"
+		if field.key != nil {
+			key = strconv.Quote(*field.key)
+		}
+		if field.optional {
+			fmt.Fprintf(
+				buf,
+				"if field%d, ok, err := manioc.OptionalField[%s](a, %s); err != nil {\n",
+				i,
+				g.typeString(field.t),
+				key,
+			)
+			fmt.Fprintf(buf, "return nil, err\n} else if ok {\nret.%s = field%d\n}\n", field.name, i)
+			continue
+		}
+		fmt.Fprintf(buf, "field%d, err := manioc.Field[%s](a, %s)\n", i, g.typeString(field.t), key)
+		fmt.Fprintf(buf, "if err != nil {\nreturn nil, err\n}\nret.%s = field%d\n", field.name, i)
+	}
+}
+
+func (g *generator) generateType(t types.Type) {
+	id := types.TypeString(t, nil)
+	if _, ok := g.entries[id]; ok || !g.isAccessible(t) {
+		return
+	}
+	buf := &bytes.Buffer{}
+	if ptr, ok := t.(*types.Pointer); ok {
+		fields, ok := g.injectedFields(ptr.Elem())
+		if !ok {
+			return
+		}
+		fmt.Fprintf(buf, "ret := new(%s)\n", g.typeString(ptr.Elem()))
+		g.writeFields(buf, fields)
+	} else {
+		// the fields of non-pointer types cannot be injected
+		if fields, ok := g.injectedFields(t); !ok || len(fields) > 0 {
+			return
+		}
+		fmt.Fprintf(buf, "var ret %s\n", g.typeString(t))
+	}
+	g.entries[id] = fmt.Sprintf(
+		"manioc.UseGeneratedType[%s](func(a *manioc.Activation) (any, error) {\n%sreturn ret, nil\n})\n",
+		g.typeString(t),
+		buf.String(),
+	)
+}
+
+func (g *generator) generateConstructor(expr ast.Expr, signature *types.Signature) {
+	fn := g.lookupFunc(expr)
+	if fn == nil || signature.Variadic() {
+		return
+	}
+	id := fn.FullName()
+	if _, ok := g.entries[id]; ok || !g.isAccessible(signature) {
+		return
+	}
+	name, ok := g.objectString(fn)
+	if !ok {
+		return
+	}
+	// the fields are injected only if the constructor returns a pointer to a struct,
+	// since the dynamic types of interfaces are unknown
+	var fields []injectedField
+	tRet := signature.Results().At(0).Type()
+	if _, isInterface := tRet.Underlying().(*types.Interface); isInterface {
+		return
+	}
+	if ptr, isPointer := tRet.(*types.Pointer); isPointer {
+		fields, ok = g.injectedFields(ptr.Elem())
+	} else {
+		// the fields of non-pointer types cannot be injected
+		fields, ok = g.injectedFields(tRet)
+		ok = ok && len(fields) == 0
+	}
+	if !ok {
+		return
+	}
+	buf := &bytes.Buffer{}
+	args := make([]string, signature.Params().Len())
+	for i := range args {
+		args[i] = fmt.Sprintf("arg%d", i)
+		fmt.Fprintf(buf, "%s, err := manioc.Argument[%s](a, %d)\n", args[i], g.typeString(signature.Params().At(i).Type()), i)
+		buf.WriteString("if err != nil {\nreturn nil, err\n}\n")
+	}
+	if signature.Results().Len() == 2 { //nolint:gomnd
+		fmt.Fprintf(buf, "ret, err := %s(%s)\n", name, strings.Join(args, ", "))
+		buf.WriteString("if err != nil {\nreturn nil, a.Fail(err)\n}\n")
+	} else {
+		fmt.Fprintf(buf, "ret := %s(%s)\n", name, strings.Join(args, ", "))
+	}
+	if len(fields) > 0 {
+		// the same as the reflection-based activation, the fields are not injected if the constructor returns nil
+		buf.WriteString("if ret != nil {\n")
+		g.writeFields(buf, fields)
+		buf.WriteString("}\n")
+	}
+	g.entries[id] = fmt.Sprintf(
+		"manioc.UseGeneratedConstructor(%s, func(a *manioc.Activation) (any, error) {\n%sreturn ret, nil\n})\n",
+		name,
+		buf.String(),
+	)
+}
+
+func (g *generator) emit() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("// Code generated by manioc gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "//go:build !%s\n\n", GeneratedBuildTag)
+	fmt.Fprintf(buf, "package %s\n\n", g.pkg.Name())
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	buf.WriteString("import (\n")
+	for _, path := range paths {
+		if name := g.imports[path]; name != pathpkg.Base(path) {
+			fmt.Fprintf(buf, "%s %s\n", name, strconv.Quote(path))
+		} else {
+			fmt.Fprintf(buf, "%s\n", strconv.Quote(path))
+		}
+	}
+	buf.WriteString(")\n\n")
+	ids := make([]string, 0, len(g.entries))
+	for id := range g.entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	buf.WriteString("func init() {\n")
+	for _, id := range ids {
+		buf.WriteString(g.entries[id])
+	}
+	buf.WriteString("}\n")
+	return format.Source(buf.Bytes())
+}
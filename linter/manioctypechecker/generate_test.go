@@ -0,0 +1,75 @@
+package manioctypechecker_test
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fuzmish/manioc/linter/manioctypechecker"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+//nolint:gochecknoglobals
+var update = flag.Bool("update", false, "update the golden files")
+
+// TestGenerate is a test for Generate.
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join(analysistest.TestData(), "src", "a", "gen")
+	// parse and type-check the test sources
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(dir, "gen.go"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  nil,
+		Selections: nil,
+		Scopes:     nil,
+		InitOrder:  nil,
+		Instances:  nil,
+	}
+	//nolint:exhaustivestruct
+	config := &types.Config{Importer: importer.ForCompiler(fset, "source", nil).(types.ImporterFrom)}
+	// resolve the imports relative to the test sources, i.e. within the module of the test sources
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+	pkg, err := config.Check("a/gen", fset, []*ast.File{file}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// generate
+	src, err := manioctypechecker.Generate(pkg, info, []*ast.File{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join(dir, "manioc_gen.go.golden")
+	if *update {
+		//nolint:gosec
+		if err := os.WriteFile(golden, src, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, src) {
+		t.Errorf("unexpected output:\n%s", src)
+	}
+}
@@ -36,12 +36,17 @@ func typeCategoryName(t types.Type) string {
 }
 
 func checkManiocPackage(pass *analysis.Pass, x ast.Expr) bool {
+	return isManiocPackage(pass.TypesInfo, x)
+}
+
+// Returns true if x refers to the manioc package.
+func isManiocPackage(info *types.Info, x ast.Expr) bool {
 	//nolint:varnamelen
 	ident, ok := x.(*ast.Ident)
 	if !ok {
 		return false
 	}
-	use, ok := pass.TypesInfo.Uses[ident]
+	use, ok := info.Uses[ident]
 	if !ok {
 		return false
 	}
@@ -53,69 +58,66 @@ func checkManiocPackage(pass *analysis.Pass, x ast.Expr) bool {
 	return path == "github.com/fuzmish/manioc"
 }
 
-func checkManiocRegisterConstructorTypeParameters(
-	pass *analysis.Pass,
-	expr ast.Expr,
+// Validates the type parameters of RegisterConstructor and its variants,
+// and returns the signature of the constructor, or the message describing the problem.
+func validateRegisterConstructorTypeParameters(
 	tTInterface types.Type,
 	tTConstructor types.Type,
-) {
+) (*types.Signature, string) {
 	// check ctor signature
 	tSignature, ok := tTConstructor.(*types.Signature)
 	if !ok {
-		pass.Report(analysis.Diagnostic{
-			Pos: expr.Pos(),
-			Message: fmt.Sprintf(
-				"The argument type should be a function type, but `%v` is given",
-				typeCategoryName(tTConstructor),
-			),
-		})
-		return
+		return nil, fmt.Sprintf(
+			"The argument type should be a function type, but `%v` is given",
+			typeCategoryName(tTConstructor),
+		)
 	}
 	// check ctor return type
 	funcRet := tSignature.Results()
 	rLen := funcRet.Len()
 	if rLen < 1 || rLen > 2 {
-		pass.Report(analysis.Diagnostic{
-			Pos:     expr.Pos(),
-			Message: "The number of function return values should be either one or two",
-		})
-		return
+		return nil, "The number of function return values should be either one or two"
 	}
 	// check the first return type
 	tRet := funcRet.At(0).Type()
 	if !types.AssignableTo(tRet, tTInterface) {
-		pass.Report(analysis.Diagnostic{
-			Pos: expr.Pos(),
-			Message: fmt.Sprintf(
-				"The type of the first return value `%v` is not assignable to `%v`",
-				tRet,
-				tTInterface,
-			),
-		})
-		return
+		return nil, fmt.Sprintf(
+			"The type of the first return value `%v` is not assignable to `%v`",
+			tRet,
+			tTInterface,
+		)
 	}
 	// check the second return type
 	if rLen != 1 {
 		tRetError := funcRet.At(1).Type()
 		tError := types.Universe.Lookup("error").Type()
 		if !types.AssignableTo(tRetError, tError) {
-			pass.Report(analysis.Diagnostic{
-				Pos: expr.Pos(),
-				Message: fmt.Sprintf(
-					"The type of the second return value should be `error`, but `%v` is given",
-					tRetError,
-				),
-			})
+			return nil, fmt.Sprintf(
+				"The type of the second return value should be `error`, but `%v` is given",
+				tRetError,
+			)
 		}
 	}
+	return tSignature, ""
 }
 
-func checkManiocRegisterTypeParameters(
+func checkManiocRegisterConstructorTypeParameters(
 	pass *analysis.Pass,
 	expr ast.Expr,
 	tTInterface types.Type,
-	tTImplementation types.Type,
+	tTConstructor types.Type,
 ) {
+	if _, message := validateRegisterConstructorTypeParameters(tTInterface, tTConstructor); message != "" {
+		pass.Report(analysis.Diagnostic{Pos: expr.Pos(), Message: message})
+	}
+}
+
+// Validates the type parameters of Register and its variants,
+// and returns the type of the activated instances, or the message describing the problem.
+func validateRegisterTypeParameters(
+	tTInterface types.Type,
+	tTImplementation types.Type,
+) (types.Type, string) {
 	// if TInterface is an interface type
 	tPtrTImplementation := tTImplementation
 	if _, ok := tTInterface.Underlying().(*types.Interface); ok {
@@ -132,26 +134,31 @@ func checkManiocRegisterTypeParameters(
 		tElmTImplementation = tPtr.Elem()
 	}
 	if _, ok := tElmTImplementation.Underlying().(*types.Interface); ok {
-		pass.Report(analysis.Diagnostic{
-			Pos: expr.Pos(),
-			Message: fmt.Sprintf(
-				"The implementation type `%v` should not be an interface",
-				tElmTImplementation,
-			),
-		})
-		return
+		return nil, fmt.Sprintf(
+			"The implementation type `%v` should not be an interface",
+			tElmTImplementation,
+		)
 	}
 
 	// check if TImplementation is assignable to TInterface
 	if !types.AssignableTo(tPtrTImplementation, tTInterface) {
-		pass.Report(analysis.Diagnostic{
-			Pos: expr.Pos(),
-			Message: fmt.Sprintf(
-				"`%v` is not assignable to `%v`",
-				tTImplementation,
-				tTInterface,
-			),
-		})
+		return nil, fmt.Sprintf(
+			"`%v` is not assignable to `%v`",
+			tTImplementation,
+			tTInterface,
+		)
+	}
+	return tPtrTImplementation, ""
+}
+
+func checkManiocRegisterTypeParameters(
+	pass *analysis.Pass,
+	expr ast.Expr,
+	tTInterface types.Type,
+	tTImplementation types.Type,
+) {
+	if _, message := validateRegisterTypeParameters(tTInterface, tTImplementation); message != "" {
+		pass.Report(analysis.Diagnostic{Pos: expr.Pos(), Message: message})
 	}
 }
 
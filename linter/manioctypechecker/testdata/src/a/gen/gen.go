@@ -0,0 +1,69 @@
+package gen
+
+import (
+	"net/http"
+
+	"github.com/fuzmish/manioc"
+)
+
+type IMyService interface {
+	doSomething()
+}
+
+type Config struct {
+	Name string
+}
+
+type MyService struct {
+	config *Config      `manioc:"inject"`
+	client *http.Client `manioc:"inject,key=client"`
+	logger func(string) `manioc:"inject,optional"`
+	others []IMyService `manioc:"inject,key=others,optional"`
+	count  int
+}
+
+func (s *MyService) doSomething() {}
+
+func NewMyService(config *Config, client *http.Client) *MyService {
+	return &MyService{config: config, client: client, logger: nil, others: nil, count: 0}
+}
+
+func NewMyServiceWithError(config *Config) (*MyService, error) {
+	return &MyService{config: config, client: nil, logger: nil, others: nil, count: 0}, nil
+}
+
+// the fields are not injected if the constructor returns nil
+func NewNilableService(config *Config) *MyService {
+	if config.Name == "" {
+		return nil
+	}
+	return &MyService{config: config, client: nil, logger: nil, others: nil, count: 0}
+}
+
+func NewIMyService() IMyService {
+	return &MyService{config: nil, client: nil, logger: nil, others: nil, count: 0}
+}
+
+// unknown tags are reported on activation
+type InvalidService struct {
+	config *Config `manioc:"inject,unknown"`
+}
+
+func (s *InvalidService) doSomething() {}
+
+func register() {
+	// generated
+	_ = manioc.Register[IMyService, MyService]()
+	_ = manioc.RegisterSingleton[*MyService, *MyService]()
+	_ = manioc.Register[Config, Config]()
+	_ = manioc.RegisterConstructor[IMyService](NewMyService)
+	_ = manioc.RegisterScopedConstructor[*MyService](NewMyServiceWithError, manioc.WithArgumentKey(0, "config"))
+	_ = manioc.RegisterTransientConstructor[*http.Request](http.NewRequest)
+	_ = manioc.Register[*http.Client, *http.Client]()
+	_ = manioc.RegisterConstructor[*MyService](NewNilableService, manioc.WithRegisterKey("nilable"))
+
+	// not generated
+	_ = manioc.Register[IMyService, InvalidService]()
+	_ = manioc.RegisterConstructor[IMyService](NewIMyService)
+	_ = manioc.RegisterConstructor[*Config](func() *Config { return &Config{Name: ""} })
+}
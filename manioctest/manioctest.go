@@ -0,0 +1,147 @@
+// Package manioctest provides the helpers to test the code using manioc containers.
+package manioctest
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+)
+
+// Isolate returns a clone of the container, which is closed when the test and all its subtests complete.
+// The registrations made in the clone do not affect the container, so the tests can modify it in parallel.
+func Isolate(t testing.TB, ctr manioc.Container) manioc.Container {
+	t.Helper()
+	clone, restore := isolate(t, ctr)
+	t.Cleanup(restore)
+	return clone
+}
+
+func isolate(t testing.TB, ctr manioc.Container) (manioc.Container, func()) {
+	t.Helper()
+	clone := ctr.Clone()
+	once := sync.Once{}
+	return clone, func() {
+		once.Do(func() {
+			if err := clone.Close(); err != nil {
+				t.Errorf("failed to close the isolated container: %v", err)
+			}
+		})
+	}
+}
+
+// Override returns a clone of the container where the registrations of T are replaced with the fake,
+// and the function to close the clone. The clone is also closed when the test and all its subtests complete.
+//
+// The fake is either an instance of T, or a constructor of T like the one given to RegisterConstructor.
+// The options are passed to RegisterInstance or RegisterConstructor, e.g. WithRegisterKey
+// to replace the registrations with the key. Since the singletons are not copied to the clone,
+// the services depending on T are created again with the fake:
+//
+//	ctr, _ := manioctest.Override[IMailer](t, app.Container, &FakeMailer{})
+//	service := manioc.MustResolve[ISignupService](manioc.WithScope(ctr))
+func Override[T any, TFake any](
+	t testing.TB,
+	ctr manioc.Container,
+	fake TFake,
+	opts ...manioc.RegisterOption,
+) (manioc.Container, func()) {
+	t.Helper()
+	clone, restore := isolate(t, ctr)
+	t.Cleanup(restore)
+	opts = append(opts, manioc.WithContainer(clone))
+	manioc.Unregister[T](opts...)
+	var err error
+	if instance, ok := any(fake).(T); ok {
+		err = manioc.RegisterInstance(instance, opts...)
+	} else if reflect.TypeOf(fake) != nil && reflect.TypeOf(fake).Kind() == reflect.Func {
+		err = manioc.RegisterConstructor[T](fake, opts...)
+	} else {
+		t.Fatalf("the fake of type `%T` is neither `%s` nor a constructor of it", fake, reflect.TypeOf((*T)(nil)).Elem())
+	}
+	if err != nil {
+		t.Fatalf("failed to register the fake: %v", err)
+	}
+	return clone, restore
+}
+
+// ActivationCounter counts the activations of a service in a container, i.e. how many times
+// the instances are created. It does not count the resolutions served from the caches.
+type ActivationCounter struct {
+	count int64
+}
+
+// Count returns the number of the activations.
+func (c *ActivationCounter) Count() int {
+	return int(atomic.LoadInt64(&c.count))
+}
+
+// CountActivations registers a decorator for T into the container, which counts the activations of T.
+// Note that it counts the activations, not the resolutions: the instances served from the caches are not
+// counted, so a service registered with GlobalCache policy is counted once however many times it is resolved,
+// and a ScopedCache service is counted once per scope. Use CountResolutions to count every resolution.
+// The options are passed to Decorate, e.g. WithRegisterKey to count the registrations with the key.
+// Use it with the containers returned by Isolate or Override, since the decorator cannot be removed
+// from the container.
+func CountActivations[T any](t testing.TB, ctr manioc.Container, opts ...manioc.RegisterOption) *ActivationCounter {
+	t.Helper()
+	counter := &ActivationCounter{count: 0}
+	err := manioc.Decorate[T](func(instance T) T {
+		atomic.AddInt64(&counter.count, 1)
+		return instance
+	}, append(opts, manioc.WithContainer(ctr))...)
+	if err != nil {
+		t.Fatalf("failed to count the activations: %v", err)
+	}
+	return counter
+}
+
+// AssertActivated reports an error if the number of the activations counted by the counter is not the expected one.
+func AssertActivated(t testing.TB, counter *ActivationCounter, expected int) bool {
+	t.Helper()
+	if actual := counter.Count(); actual != expected {
+		t.Errorf("expected to be activated %d times, but activated %d times", expected, actual)
+		return false
+	}
+	return true
+}
+
+// ResolutionCounter counts the resolutions of a service in a container,
+// including the ones served from the caches.
+type ResolutionCounter struct {
+	count int64
+}
+
+// Count returns the number of the resolutions.
+func (c *ResolutionCounter) Count() int {
+	return int(atomic.LoadInt64(&c.count))
+}
+
+// CountResolutions registers a function for T into the container with OnResolved, which counts the resolutions
+// of T, i.e. how many times T is resolved, including the instances served from the caches and the ones injected
+// into other services. The options are passed to OnResolved, e.g. WithRegisterKey to count the registrations
+// with the key. Use it with the containers returned by Isolate or Override, since the function cannot be removed
+// from the container.
+func CountResolutions[T any](t testing.TB, ctr manioc.Container, opts ...manioc.RegisterOption) *ResolutionCounter {
+	t.Helper()
+	counter := &ResolutionCounter{count: 0}
+	err := manioc.OnResolved(func(instance T) {
+		atomic.AddInt64(&counter.count, 1)
+	}, append(opts, manioc.WithContainer(ctr))...)
+	if err != nil {
+		t.Fatalf("failed to count the resolutions: %v", err)
+	}
+	return counter
+}
+
+// AssertResolved reports an error if the number of the resolutions counted by the counter is not the expected one.
+func AssertResolved(t testing.TB, counter *ResolutionCounter, expected int) bool {
+	t.Helper()
+	if actual := counter.Count(); actual != expected {
+		t.Errorf("expected to be resolved %d times, but resolved %d times", expected, actual)
+		return false
+	}
+	return true
+}
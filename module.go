@@ -0,0 +1,169 @@
+package manioc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrModuleAlreadyInstalled indicates that the module, or another module with the same name,
+// has already been installed into the container.
+var ErrModuleAlreadyInstalled = errors.New("module already installed")
+
+// Module is a named set of registrations, which can be installed into a container.
+type Module struct {
+	name     string
+	setup    func(ctr Container) error
+	requires []*Module
+}
+
+// NewModule creates a new module. The setup function is called with the container
+// when the module is installed, and it should register the dependencies into the container:
+//
+//	var MyModule = manioc.NewModule("my-module", func(ctr manioc.Container) error {
+//		return manioc.Register[IMyService, MyService](manioc.WithContainer(ctr))
+//	}, OtherModule)
+//
+// The modules passed as `requires` are installed before this module.
+func NewModule(name string, setup func(ctr Container) error, requires ...*Module) *Module {
+	if setup == nil {
+		panic(fmt.Errorf("the setup function of module `%s` should not be nil", name))
+	}
+	return &Module{name: name, setup: setup, requires: requires}
+}
+
+// Name returns the name of the module.
+func (m *Module) Name() string {
+	return m.name
+}
+
+// Requires returns the modules that the module depends on.
+func (m *Module) Requires() []*Module {
+	return append([]*Module{}, m.requires...)
+}
+
+// moduleRegistry records the modules installed in a container.
+type moduleRegistry struct {
+	mu sync.Mutex
+	// the installed (or being installed) modules by name
+	modules map[string]*Module
+	// the names of the installed modules, in installation order
+	installed []string
+}
+
+func newModuleRegistry() *moduleRegistry {
+	return &moduleRegistry{
+		modules:   make(map[string]*Module),
+		installed: make([]string, 0),
+	}
+}
+
+// Returns a copy of this registry with the installed modules.
+// The modules being installed are not copied.
+func (r *moduleRegistry) clone() *moduleRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := newModuleRegistry()
+	for _, name := range r.installed {
+		ret.modules[name] = r.modules[name]
+		ret.installed = append(ret.installed, name)
+	}
+	return ret
+}
+
+// Mark the module as being installed. Returns false if the module has already been installed.
+func (r *moduleRegistry) begin(m *Module) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if installed, ok := r.modules[m.name]; ok {
+		if installed != m {
+			return false, fmt.Errorf("%w: another module with the same name `%s`", ErrModuleAlreadyInstalled, m.name)
+		}
+		return false, nil
+	}
+	r.modules[m.name] = m
+	return true, nil
+}
+
+func (r *moduleRegistry) commit(m *Module) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.installed = append(r.installed, m.name)
+}
+
+func (r *moduleRegistry) abort(m *Module) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.modules, m.name)
+}
+
+func (r *moduleRegistry) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.installed...)
+}
+
+// Install installs the modules and the modules they require into the container.
+// Each module is installed only once; the required modules that have already been installed are skipped,
+// but installing the same module explicitly twice fails with ErrModuleAlreadyInstalled.
+// If the setup of a module fails, the error names the module, and the module is not marked as installed.
+// Note that the registrations made by the failed setup are not rolled back.
+func Install(ctr Container, modules ...*Module) error {
+	registry := ctr.getModuleRegistry()
+	for _, m := range modules {
+		ok, err := registry.begin(m)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: `%s`", ErrModuleAlreadyInstalled, m.name)
+		}
+		if err := installModule(ctr, registry, m, []string{m.name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Install the module marked as being installed, after its required modules.
+func installModule(ctr Container, registry *moduleRegistry, m *Module, path []string) error {
+	for _, required := range m.requires {
+		// detect circular requirement
+		for _, name := range path {
+			if name == required.name {
+				registry.abort(m)
+				return fmt.Errorf(
+					"failed to install module `%s`: circular requirement: %s -> %s",
+					m.name,
+					strings.Join(path, " -> "),
+					required.name,
+				)
+			}
+		}
+		ok, err := registry.begin(required)
+		if err != nil {
+			registry.abort(m)
+			return fmt.Errorf("failed to install module `%s`: %w", m.name, err)
+		}
+		if !ok {
+			// already installed
+			continue
+		}
+		if err := installModule(ctr, registry, required, append(path, required.name)); err != nil {
+			registry.abort(m)
+			return fmt.Errorf("failed to install module `%s`: %w", m.name, err)
+		}
+	}
+	if err := m.setup(ctr); err != nil {
+		registry.abort(m)
+		return fmt.Errorf("failed to install module `%s`: %w", m.name, err)
+	}
+	registry.commit(m)
+	return nil
+}
+
+// InstalledModules returns the names of the modules installed in the container, in installation order.
+func InstalledModules(ctr Container) []string {
+	return ctr.getModuleRegistry().list()
+}
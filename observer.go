@@ -0,0 +1,53 @@
+package manioc
+
+import (
+	"errors"
+)
+
+// observingActivator calls the observers registered for the key with the resolved instance,
+// including the instances served from the caches.
+type observingActivator struct {
+	baseActivator activator
+	key           registryKey
+	// the registry where the activator is registered
+	owner *registry
+}
+
+func (e *observingActivator) activate(ctx resolveContext) (any, error) {
+	instance, err := e.baseActivator.activate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, fn := range e.owner.getObservers(e.key) {
+		fn(instance)
+	}
+	return instance, nil
+}
+
+func (e *observingActivator) dependencies() ([]dependency, error) {
+	return e.baseActivator.dependencies()
+}
+
+// OnResolved registers a function for the service type T, which is called with the instance every time T is
+// resolved, including the instances served from the caches. It is useful to observe how the services are used,
+// e.g. to count the resolutions in tests, while Decorate and WithOnActivated are called only on activation.
+//
+// The function applies to all registrations of T with the key specified by WithRegisterKey,
+// including the registrations made after the call of OnResolved, in the same way as Decorate.
+// If multiple functions are registered, they are called in the order of registration.
+func OnResolved[T any](fn func(instance T), opts ...RegisterOption) error {
+	if fn == nil {
+		panic(errors.New("the function for OnResolved should not be nil"))
+	}
+	options := mergeRegisterOptions(opts)
+	ctx := options.container.getRegisterContext()
+	if ctx == nil {
+		return newScopeClosedError(typeof[T](), options.key)
+	}
+	key := registryKey{serviceType: typeof[T](), serviceKey: options.key}
+	return ctx.observe(key, func(instance any) {
+		// the zero value for nil instances
+		value, _ := instance.(T)
+		fn(value)
+	})
+}
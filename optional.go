@@ -0,0 +1,95 @@
+package manioc
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Optional is a wrapper of a dependency which may not be registered.
+// An Optional can be injected via constructor or field injection, in place of the dependency itself:
+//
+//	func NewMyService(foo manioc.Optional[IFooService]) *MyService { ... }
+//
+// If the dependency is not registered, an empty Optional is injected.
+// Errors other than the missing registration, such as constructor errors, fail the resolution.
+type Optional[T any] struct {
+	value T
+	ok    bool
+}
+
+// Get returns the dependency, and whether it was registered.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.ok
+}
+
+// OrElse returns the dependency if it was registered, otherwise returns the given value.
+func (o Optional[T]) OrElse(value T) T {
+	if o.ok {
+		return o.value
+	}
+	return value
+}
+
+func (o *Optional[T]) setOptional(value any) {
+	//nolint:forcetypeassert
+	o.value, o.ok = value.(T), true
+}
+
+func (o *Optional[T]) optionalElemType() reflect.Type {
+	return typeof[T]()
+}
+
+// optionalSetter is implemented by the pointer to Optional[T].
+type optionalSetter interface {
+	setOptional(value any)
+	optionalElemType() reflect.Type
+}
+
+// Returns the element key if the service type is Optional[T].
+func optionalElemKey(key registryKey) (registryKey, bool) {
+	if key.serviceType.Kind() != reflect.Struct {
+		return registryKey{}, false
+	}
+	setter, ok := reflect.New(key.serviceType).Interface().(optionalSetter)
+	if !ok {
+		return registryKey{}, false
+	}
+	return registryKey{serviceType: setter.optionalElemType(), serviceKey: key.serviceKey}, true
+}
+
+// Returns true if the error reports that the key requested at the given depth of the chain
+// is not registered, rather than any of its dependencies.
+func isKeyNotRegistered(err error, key registryKey, depth int) bool {
+	var resolveErr *ResolveError
+	return errors.As(err, &resolveErr) &&
+		resolveErr.Err == ErrNotRegistered && //nolint:errorlint,goerr113
+		resolveErr.ServiceType == key.serviceType &&
+		resolveErr.ServiceKey == key.serviceKey &&
+		len(resolveErr.Path) == depth
+}
+
+// Resolve the key requested within this frame. The second return value is false
+// if the key itself is not registered. Other errors are returned as they are.
+func (f *resolveFrame) resolveOptional(key registryKey) (any, bool, error) {
+	instance, err := f.resolve(key)
+	if err != nil {
+		if isKeyNotRegistered(err, key, len(f.path())) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return instance, true, nil
+}
+
+func (f *resolveFrame) resolveOptionalWrapper(key registryKey, elemKey registryKey) (any, error) {
+	ret := reflect.New(key.serviceType)
+	instance, ok, err := f.resolveOptional(elemKey)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		//nolint:forcetypeassert
+		ret.Interface().(optionalSetter).setOptional(instance)
+	}
+	return ret.Elem().Interface(), nil
+}
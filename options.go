@@ -2,13 +2,18 @@ package manioc
 
 import (
 	"fmt"
+	"time"
 )
 
 // options for Register
 type registerOptions struct {
-	container Container
-	key       any
-	policy    CachePolicy
+	container    Container
+	key          any
+	policy       CachePolicy
+	argumentKeys map[int]any
+	onActivated  []func(instance any) error
+	// defaultConflictPolicy if not specified
+	conflictPolicy ConflictPolicy
 }
 
 type RegisterOption interface {
@@ -56,6 +61,47 @@ func WithCachePolicy(policy CachePolicy) RegisterOption {
 	}
 }
 
+// WithArgumentKey
+
+type withArgumentKey struct {
+	index int
+	key   any
+}
+
+func (opt *withArgumentKey) apply(options *registerOptions) {
+	options.argumentKeys[opt.index] = opt.key
+}
+
+// WithArgumentKey specifies the service key to resolve the argument of the constructor
+// at the given index (starting from 0). It is only available for RegisterConstructor and its variants.
+func WithArgumentKey(index int, key any) RegisterOption {
+	return &withArgumentKey{index: index, key: key}
+}
+
+// WithConflictPolicy
+
+type withConflictPolicy struct{ policy ConflictPolicy }
+
+func (opt *withConflictPolicy) apply(options *registerOptions) {
+	options.conflictPolicy = opt.policy
+}
+
+func validateConflictPolicy(policy ConflictPolicy) {
+	switch policy {
+	case Append, Replace, KeepExisting, FailOnDuplicate:
+		return
+	default:
+		panic(fmt.Errorf("invalid ConflictPolicy value: `%v`", policy))
+	}
+}
+
+// WithConflictPolicy specifies how the registration is handled when the service has already been registered
+// with the same key. The default is the one of the container, given by WithDefaultConflictPolicy.
+func WithConflictPolicy(policy ConflictPolicy) RegisterOption {
+	validateConflictPolicy(policy)
+	return &withConflictPolicy{policy: policy}
+}
+
 //
 // options for Resolve
 //
@@ -116,3 +162,101 @@ func (opt *withCacheMode) apply(options *openScopeOptions) {
 func WithCacheMode(cacheMode ScopeCacheMode) OpenScopeOption {
 	return &withCacheMode{cacheMode: cacheMode}
 }
+
+//
+// options for Start, Stop and Run
+//
+
+type lifecycleOptions struct {
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+}
+
+type LifecycleOption interface {
+	apply(*lifecycleOptions)
+}
+
+func mergeLifecycleOptions(opts []LifecycleOption) *lifecycleOptions {
+	options := &lifecycleOptions{
+		startTimeout: 0,
+		stopTimeout:  0,
+	}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	return options
+}
+
+// WithStartTimeout
+
+type withStartTimeout struct{ timeout time.Duration }
+
+func (opt *withStartTimeout) apply(options *lifecycleOptions) {
+	options.startTimeout = opt.timeout
+}
+
+// WithStartTimeout limits the duration of the start phase. There is no limit by default.
+func WithStartTimeout(timeout time.Duration) LifecycleOption {
+	return &withStartTimeout{timeout: timeout}
+}
+
+// WithStopTimeout
+
+type withStopTimeout struct{ timeout time.Duration }
+
+func (opt *withStopTimeout) apply(options *lifecycleOptions) {
+	options.stopTimeout = opt.timeout
+}
+
+// WithStopTimeout limits the duration of the stop phase. There is no limit by default.
+func WithStopTimeout(timeout time.Duration) LifecycleOption {
+	return &withStopTimeout{timeout: timeout}
+}
+
+//
+// options for NewContainer
+//
+
+type containerOptions struct {
+	captiveMode    CaptiveMode
+	conflictPolicy ConflictPolicy
+}
+
+type ContainerOption interface {
+	apply(*containerOptions)
+}
+
+// WithCaptiveMode
+
+type withCaptiveMode struct{ captiveMode CaptiveMode }
+
+func (opt *withCaptiveMode) apply(options *containerOptions) {
+	options.captiveMode = opt.captiveMode
+}
+
+// WithCaptiveMode configures how the container handles captive dependencies.
+// The default is DefaultCaptiveMode.
+func WithCaptiveMode(captiveMode CaptiveMode) ContainerOption {
+	switch captiveMode {
+	case DefaultCaptiveMode, StrictCaptiveMode, IgnoreCaptiveMode:
+		return &withCaptiveMode{captiveMode: captiveMode}
+	default:
+		panic(fmt.Errorf("invalid CaptiveMode value: `%v`", captiveMode))
+	}
+}
+
+// WithDefaultConflictPolicy
+
+type withDefaultConflictPolicy struct{ policy ConflictPolicy }
+
+func (opt *withDefaultConflictPolicy) apply(options *containerOptions) {
+	options.conflictPolicy = opt.policy
+}
+
+// WithDefaultConflictPolicy configures how the container handles the registrations of the services
+// which have already been registered, unless WithConflictPolicy is specified. The default is Append.
+// The child containers inherit the default of the parent.
+func WithDefaultConflictPolicy(policy ConflictPolicy) ContainerOption {
+	validateConflictPolicy(policy)
+	return &withDefaultConflictPolicy{policy: policy}
+}
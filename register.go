@@ -6,9 +6,12 @@ import (
 
 func mergeRegisterOptions(opts []RegisterOption) *registerOptions {
 	options := &registerOptions{
-		container: globalContainer,
-		key:       nil,
-		policy:    NeverCache,
+		container:      GlobalContainer(),
+		key:            nil,
+		policy:         NeverCache,
+		argumentKeys:   make(map[int]any),
+		onActivated:    nil,
+		conflictPolicy: defaultConflictPolicy,
 	}
 	for _, opt := range opts {
 		opt.apply(options)
@@ -19,30 +22,31 @@ func mergeRegisterOptions(opts []RegisterOption) *registerOptions {
 func IsRegistered[T any](opts ...RegisterOption) bool {
 	options := mergeRegisterOptions(opts)
 	ctx := options.container.getRegisterContext()
+	if ctx == nil {
+		return false
+	}
 	key := registryKey{serviceType: typeof[T](), serviceKey: options.key}
 	return ctx.isRegistered(key)
 }
 
-func register(serviceType reflect.Type, activator activator, opts ...RegisterOption) error {
-	// parse option
-	options := mergeRegisterOptions(opts)
+func register(serviceType reflect.Type, base activator, options *registerOptions) error {
 	// get context
 	ctx := options.container.getRegisterContext()
-	// install field injection activator
-	activator = &fieldInjectionActivator{baseActivator: activator}
-	// install cache activator
-	activator = &cacheActivator{baseActivator: activator, policy: options.policy}
-	// register
+	if ctx == nil {
+		return newScopeClosedError(serviceType, options.key)
+	}
 	key := registryKey{serviceType: serviceType, serviceKey: options.key}
-	return ctx.register(key, activator)
+	// register
+	return ctx.register(key, base, options)
 }
 
 func RegisterConstructor[T any, TConstructor any](ctor TConstructor, opts ...RegisterOption) error {
-	activator, err := newConstructorActivator[T](ctor)
+	options := mergeRegisterOptions(opts)
+	activator, err := newConstructorActivator[T](ctor, options.argumentKeys)
 	if err != nil {
 		return err
 	}
-	return register(typeof[T](), activator, opts...)
+	return register(typeof[T](), activator, options)
 }
 
 func RegisterInstance[T any](instance T, opts ...RegisterOption) error {
@@ -51,18 +55,57 @@ func RegisterInstance[T any](instance T, opts ...RegisterOption) error {
 		return err
 	}
 	// override cache policy
-	opts = append(opts, WithCachePolicy(GlobalCache))
-	return register(typeof[T](), activator, opts...)
+	options := mergeRegisterOptions(append(opts, WithCachePolicy(GlobalCache)))
+	return register(typeof[T](), activator, options)
+}
+
+// RegisterScopedInstance registers the instance only for the scope, e.g. the values specific to a request.
+// The instance takes precedence over the registrations in the container, and it is visible only to
+// the resolutions within the scope. The child scopes opened with InheritCacheMode or SyncCacheMode
+// can also resolve the instance. The instance is discarded when the scope is closed, without disposal.
+// Only the WithRegisterKey and WithConflictPolicy options are effective. The conflicts are checked
+// against the instances registered for the same scope, with the default policy of the container if not specified.
+func RegisterScopedInstance[T any](scope Scope, instance T, opts ...RegisterOption) error {
+	activator, err := newInstanceActivator(instance)
+	if err != nil {
+		return err
+	}
+	options := mergeRegisterOptions(opts)
+	key := registryKey{serviceType: typeof[T](), serviceKey: options.key}
+	ctx := scope.getScopedRegisterContext()
+	if ctx == nil {
+		return newScopeClosedError(key.serviceType, key.serviceKey)
+	}
+	return ctx.registerScoped(key, activator, options)
+}
+
+// DeclareScopedInstance declares that the instance of T is registered for the scopes with RegisterScopedInstance,
+// e.g. by the middleware opening a scope per request. Validate and Graph treat the declared key as resolved,
+// with ScopedCache policy for the captive dependency checks. The resolutions are not affected;
+// the resolution of T within a scope without the instance fails with ErrNotRegistered.
+// Only the WithContainer and WithRegisterKey options are effective. Declaring the key again has no effect.
+func DeclareScopedInstance[T any](opts ...RegisterOption) error {
+	options := mergeRegisterOptions(opts)
+	key := registryKey{serviceType: typeof[T](), serviceKey: options.key}
+	ctx := options.container.getRegisterContext()
+	if ctx == nil {
+		return newScopeClosedError(key.serviceType, key.serviceKey)
+	}
+	ctx.declareScoped(key)
+	return nil
 }
 
 func Register[TInterface any, TImplementation any](opts ...RegisterOption) error {
 	activator := newImplementationActivator[TInterface, TImplementation]()
-	return register(typeof[TInterface](), activator, opts...)
+	return register(typeof[TInterface](), activator, mergeRegisterOptions(opts))
 }
 
 func Unregister[T any](opts ...RegisterOption) bool {
 	options := mergeRegisterOptions(opts)
 	ctx := options.container.getRegisterContext()
+	if ctx == nil {
+		return false
+	}
 	key := registryKey{serviceType: typeof[T](), serviceKey: options.key}
 	return ctx.unregister(key)
 }
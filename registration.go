@@ -0,0 +1,153 @@
+package manioc
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// RegistrationKind is an enumerated type that specifies how a registration activates instances.
+type RegistrationKind int
+
+const (
+	// The instance is created from the implementation type, registered with Register.
+	TypeRegistration RegistrationKind = iota
+	// The instance is created by the constructor, registered with RegisterConstructor.
+	ConstructorRegistration
+	// The instance is given by the user, registered with RegisterInstance.
+	InstanceRegistration
+)
+
+func (k RegistrationKind) String() string {
+	switch k {
+	case TypeRegistration:
+		return "type"
+	case ConstructorRegistration:
+		return "constructor"
+	case InstanceRegistration:
+		return "instance"
+	default:
+		return fmt.Sprintf("RegistrationKind(%d)", int(k))
+	}
+}
+
+// Registration describes a registration in a container.
+type Registration struct {
+	ServiceType reflect.Type
+	ServiceKey  any
+	CachePolicy CachePolicy
+	Kind        RegistrationKind
+	// The implementation type for TypeRegistration, the function type of the constructor
+	// for ConstructorRegistration, or the dynamic type of the instance for InstanceRegistration.
+	// For the instances declared with DeclareScopedInstance, it is the service type.
+	Implementation reflect.Type
+	// The location in the source code where the registration was made, in the form of `file:line`.
+	// It is empty if the location is unknown.
+	Site string
+}
+
+func (r Registration) String() string {
+	return fmt.Sprintf(
+		"%s <- %s %s (%s) at %s",
+		Dependency{ServiceType: r.ServiceType, ServiceKey: r.ServiceKey},
+		r.Kind,
+		r.Implementation,
+		r.CachePolicy,
+		r.Site,
+	)
+}
+
+// registration is an activator registered in the registry, with its description.
+type registration struct {
+	activator
+	// the registry where the registration is made
+	owner *registry
+	info  Registration
+	// the key of the instances of the registration in the caches
+	cacheKey any
+	// the activator given on registration, and the functions given by WithOnActivated,
+	// which are used to rebuild the registration for another registry
+	base        activator
+	onActivated []func(instance any) error
+}
+
+func newRegistration(
+	key registryKey,
+	base activator,
+	entry activator,
+	owner *registry,
+	policy CachePolicy,
+) *registration {
+	info := Registration{
+		ServiceType:    key.serviceType,
+		ServiceKey:     key.serviceKey,
+		CachePolicy:    policy,
+		Kind:           TypeRegistration,
+		Implementation: nil,
+		Site:           callerSite(),
+	}
+	switch base := base.(type) {
+	case *constructorActivator:
+		info.Kind = ConstructorRegistration
+		info.Implementation = reflect.TypeOf(base.constructor)
+	case *instanceActivator:
+		info.Kind = InstanceRegistration
+		info.Implementation = base.instanceType()
+	case *declaredActivator:
+		info.Kind = InstanceRegistration
+		info.Implementation = base.instanceType()
+	case typedActivator:
+		info.Implementation = base.instanceType()
+	}
+	return &registration{activator: entry, owner: owner, info: info, cacheKey: entry, base: base, onActivated: nil}
+}
+
+// Returns the dependencies of the registration for the key, including the ones of its decorators.
+func (r *registration) dependenciesFor(key registryKey) ([]dependency, error) {
+	deps, err := r.dependencies()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range r.owner.getDecorators(key) {
+		deps = append(deps, d.dependencies()...)
+	}
+	return deps, nil
+}
+
+// Returns the location of the nearest caller outside of this package, in the form of `file:line`.
+func callerSite() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !isInternalFrame(frame.Function) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func isInternalFrame(function string) bool {
+	// the registrations made by manioctest are attributed to the tests calling it
+	for _, pkg := range []string{"github.com/fuzmish/manioc.", "github.com/fuzmish/manioc/manioctest."} {
+		if strings.HasPrefix(function, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// Registrations returns the descriptions of all registrations in the container.
+// They are sorted by the service type and key, and then by the order of registration.
+// It returns an empty slice if the container has been closed.
+func Registrations(container Container) []Registration {
+	ctx := container.getRegisterContext()
+	if ctx == nil {
+		return []Registration{}
+	}
+	return ctx.registrations()
+}
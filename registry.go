@@ -0,0 +1,298 @@
+package manioc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// registry is a goroutine-safe store of registrations, decorators and observers.
+// If it has a parent, the registrations of the parent are inherited.
+type registry struct {
+	mu         sync.RWMutex
+	parent     *registry
+	entries    map[registryKey][]*registration
+	decorators map[registryKey][]*decorator
+	observers  map[registryKey][]func(instance any)
+	// the keys declared with DeclareScopedInstance, which are provided by the scopes
+	declared map[registryKey]*registration
+}
+
+func newRegistry(parent *registry) *registry {
+	return &registry{
+		parent:     parent,
+		entries:    make(map[registryKey][]*registration),
+		decorators: make(map[registryKey][]*decorator),
+		// allocated on the first observer, since few registries have any
+		observers: nil,
+		declared:  nil,
+	}
+}
+
+func (r *registry) add(key registryKey, entry *registration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[key] = append(r.entries[key], entry)
+}
+
+// Adds the registration for the key according to the conflict policy.
+// Only the registrations in this registry are considered as conflicts, not the ones in the parent.
+func (r *registry) addWithPolicy(key registryKey, entry *registration, policy ConflictPolicy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing := r.entries[key]
+	if len(existing) == 0 {
+		r.entries[key] = append(existing, entry)
+		return nil
+	}
+	switch policy {
+	case Append:
+		r.entries[key] = append(existing, entry)
+	case Replace:
+		r.entries[key] = []*registration{entry}
+	case KeepExisting:
+		break
+	case FailOnDuplicate:
+		return newDuplicateRegistrationError(key, existing, entry)
+	default:
+		panic(fmt.Errorf("invalid ConflictPolicy value: `%v`", policy))
+	}
+	return nil
+}
+
+// Returns the registrations for the key.
+// If no registration is found, the ones in the parent are returned.
+// The returned slice is a snapshot and is safe to use without holding the lock.
+func (r *registry) get(key registryKey) []*registration {
+	r.mu.RLock()
+	entries := r.entries[key]
+	r.mu.RUnlock()
+	if len(entries) == 0 && r.parent != nil {
+		return r.parent.get(key)
+	}
+	return entries[:len(entries):len(entries)]
+}
+
+// targetKind is an enumerated type that specifies how a key is resolved.
+type targetKind int
+
+const (
+	// resolved with the registrations for the key; it is ambiguous if there are multiple ones
+	targetRegistered targetKind = iota
+	// []T, resolved with all registrations for T
+	targetAll
+	// Lazy[T] or func() (T, error), resolving T later
+	targetDeferred
+	// Optional[T], resolving T if registered
+	targetOptional
+	// context.Context, injected by the container
+	targetContext
+	targetNotRegistered
+)
+
+// dependencyTarget describes how a key is resolved.
+type dependencyTarget struct {
+	kind targetKind
+	// the key which the registrations are looked up with; the element key for []T, Lazy[T] and so on
+	key     registryKey
+	entries []*registration
+}
+
+// Look up how the key is resolved, in the same way as resolveFrame.resolve, without activating anything.
+// The element keys of Lazy[T], func() (T, error) and Optional[T] are not looked up.
+func (r *registry) lookupTarget(key registryKey) dependencyTarget {
+	if entries := r.get(key); len(entries) > 0 {
+		return dependencyTarget{kind: targetRegistered, key: key, entries: entries}
+	}
+	// if service type is []T, look up with T
+	if key.serviceType.Kind() == reflect.Slice {
+		elemKey := registryKey{serviceType: key.serviceType.Elem(), serviceKey: key.serviceKey}
+		return dependencyTarget{kind: targetAll, key: elemKey, entries: r.get(elemKey)}
+	}
+	if elemKey, ok := lazyElemKey(key); ok {
+		return dependencyTarget{kind: targetDeferred, key: elemKey, entries: nil}
+	}
+	if elemKey, ok := factoryElemKey(key); ok {
+		return dependencyTarget{kind: targetDeferred, key: elemKey, entries: nil}
+	}
+	if elemKey, ok := optionalElemKey(key); ok {
+		return dependencyTarget{kind: targetOptional, key: elemKey, entries: nil}
+	}
+	if isContextKey(key) {
+		return dependencyTarget{kind: targetContext, key: key, entries: nil}
+	}
+	// the instances registered for the scopes are resolved in the same way as the registrations
+	if entry := r.getDeclared(key); entry != nil {
+		return dependencyTarget{kind: targetRegistered, key: key, entries: []*registration{entry}}
+	}
+	return dependencyTarget{kind: targetNotRegistered, key: key, entries: nil}
+}
+
+func (r *registry) addDecorator(key registryKey, d *decorator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decorators[key] = append(r.decorators[key], d)
+}
+
+// Returns the decorators registered for the key, in the order of registration.
+// The decorators in the parent come first.
+func (r *registry) getDecorators(key registryKey) []*decorator {
+	r.mu.RLock()
+	decorators := r.decorators[key]
+	r.mu.RUnlock()
+	if r.parent != nil {
+		inherited := r.parent.getDecorators(key)
+		if len(inherited) > 0 {
+			return append(inherited, decorators...)
+		}
+	}
+	return decorators[:len(decorators):len(decorators)]
+}
+
+func (r *registry) addObserver(key registryKey, fn func(instance any)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.observers == nil {
+		r.observers = make(map[registryKey][]func(instance any))
+	}
+	r.observers[key] = append(r.observers[key], fn)
+}
+
+// Returns the observers registered for the key, in the order of registration.
+// The observers in the parent come first.
+func (r *registry) getObservers(key registryKey) []func(instance any) {
+	r.mu.RLock()
+	observers := r.observers[key]
+	r.mu.RUnlock()
+	if r.parent != nil {
+		inherited := r.parent.getObservers(key)
+		if len(inherited) > 0 {
+			return append(inherited, observers...)
+		}
+	}
+	return observers[:len(observers):len(observers)]
+}
+
+// Declares that the instance for the key is provided by the scopes. Declaring the key again has no effect.
+func (r *registry) declare(key registryKey, entry *registration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.declared == nil {
+		r.declared = make(map[registryKey]*registration)
+	}
+	if _, ok := r.declared[key]; !ok {
+		r.declared[key] = entry
+	}
+}
+
+// Returns the declaration for the key, or the one in the parent. It returns nil if the key is not declared.
+func (r *registry) getDeclared(key registryKey) *registration {
+	r.mu.RLock()
+	entry := r.declared[key]
+	r.mu.RUnlock()
+	if entry == nil && r.parent != nil {
+		return r.parent.getDeclared(key)
+	}
+	return entry
+}
+
+// Returns all declared keys and their declarations, including the ones inherited from the parent.
+// The keys are sorted by their string representation, to make the order deterministic.
+func (r *registry) allDeclared() ([]registryKey, map[registryKey]*registration) {
+	declared := make(map[registryKey]*registration)
+	if r.parent != nil {
+		_, declared = r.parent.allDeclared()
+	}
+	r.mu.RLock()
+	for key, entry := range r.declared {
+		// override the inherited declarations
+		declared[key] = entry
+	}
+	r.mu.RUnlock()
+	keys := make([]registryKey, 0, len(declared))
+	for key := range declared {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys, declared
+}
+
+// Removes the registrations for the key. The ones in the parent are not removed.
+func (r *registry) remove(key registryKey) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries[key]) > 0 {
+		delete(r.entries, key)
+		return true
+	}
+	return false
+}
+
+// Returns all keys and registrations in the registry, including the ones inherited from the parent.
+// The keys are sorted by their string representation, to make the order deterministic.
+func (r *registry) all() ([]registryKey, map[registryKey][]*registration) {
+	entries := make(map[registryKey][]*registration)
+	if r.parent != nil {
+		_, entries = r.parent.all()
+	}
+	r.mu.RLock()
+	for key, value := range r.entries {
+		if len(value) == 0 {
+			continue
+		}
+		// override the inherited registrations
+		entries[key] = value[:len(value):len(value)]
+	}
+	r.mu.RUnlock()
+	keys := make([]registryKey, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys, entries
+}
+
+// Returns the registrations in this registry, excluding the parent, in no particular order.
+func (r *registry) localEntries() []*registration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var ret []*registration
+	for _, list := range r.entries {
+		ret = append(ret, list...)
+	}
+	return ret
+}
+
+// Returns the copies of the registrations, the decorators, the observers and the declarations in this registry,
+// excluding the parent.
+func (r *registry) local() (
+	map[registryKey][]*registration,
+	map[registryKey][]*decorator,
+	map[registryKey][]func(instance any),
+	map[registryKey]*registration,
+) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entries := make(map[registryKey][]*registration, len(r.entries))
+	for key, value := range r.entries {
+		entries[key] = append([]*registration{}, value...)
+	}
+	decorators := make(map[registryKey][]*decorator, len(r.decorators))
+	for key, value := range r.decorators {
+		decorators[key] = append([]*decorator{}, value...)
+	}
+	observers := make(map[registryKey][]func(instance any), len(r.observers))
+	for key, value := range r.observers {
+		observers[key] = append([]func(instance any){}, value...)
+	}
+	declared := make(map[registryKey]*registration, len(r.declared))
+	for key, entry := range r.declared {
+		declared[key] = entry
+	}
+	return entries, decorators, observers, declared
+}
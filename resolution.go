@@ -0,0 +1,228 @@
+package manioc
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+)
+
+// resolveFrame represents a registry key in the chain of resolutions in progress.
+// Activators receive the frame of the key they are activated for, so that
+// the dependencies they resolve are chained to it.
+type resolveFrame struct {
+	context *defaultContext
+	// nil for the root frame, which is not associated with any key
+	parent *resolveFrame
+	// the frame created for the key by push, which is shared by the copies of the frame,
+	// e.g. the ones bound to another context, to identify the activation across the caches
+	activation *resolveFrame
+	key        registryKey
+	// the cache policy of the registration being activated, which is set on activation
+	policy CachePolicy
+	// the context.Context of the resolution, which is never nil
+	ctx context.Context
+	// the context which the Lazy and the factory functions resolved within this frame are bound to,
+	// i.e. the context which owns the cache of the instance being activated
+	binding *defaultContext
+	// set to non-zero when the activation within this frame has finished.
+	// it is accessed atomically, since the Lazy and the factory functions may be called from other goroutines.
+	finished int32
+}
+
+func (f *resolveFrame) push(key registryKey) (*resolveFrame, error) {
+	// honor the cancellation of the resolution
+	if err := f.ctx.Err(); err != nil {
+		return nil, f.newErrorFor(key, ErrCanceled, err)
+	}
+	// detect circular dependency
+	for frame := f; frame.parent != nil; frame = frame.parent {
+		if frame.key == key {
+			return nil, f.newErrorFor(key, ErrCircularDependency, nil)
+		}
+	}
+	ret := &resolveFrame{
+		context:    f.context,
+		parent:     f,
+		activation: nil,
+		key:        key,
+		policy:     NeverCache,
+		ctx:        f.ctx,
+		binding:    f.binding,
+		finished:   0,
+	}
+	ret.activation = ret
+	return ret, nil
+}
+
+// Returns true if this frame is the one of the activation, or one of its descendants.
+func (f *resolveFrame) isWithin(activation *resolveFrame) bool {
+	for frame := f; frame != nil; frame = frame.parent {
+		if frame.activation == activation.activation {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the keys from the beginning of the chain to this frame.
+func (f *resolveFrame) path() []Dependency {
+	ret := make([]Dependency, 0)
+	for frame := f; frame.parent != nil; frame = frame.parent {
+		ret = append(ret, Dependency{ServiceType: frame.key.serviceType, ServiceKey: frame.key.serviceKey})
+	}
+	// reverse
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+	return ret
+}
+
+// Create an error for the failure to resolve the key requested within this frame.
+func (f *resolveFrame) newErrorFor(key registryKey, err error, cause error) error {
+	return &ResolveError{
+		Err:         err,
+		ServiceType: key.serviceType,
+		ServiceKey:  key.serviceKey,
+		Path:        f.path(),
+		Cause:       cause,
+	}
+}
+
+// Create an error for the failure to activate the key of this frame.
+func (f *resolveFrame) newError(err error, cause error) error {
+	if f.parent == nil {
+		// the root frame, i.e. direct resolution
+		return &ResolveError{Err: err, ServiceType: nil, ServiceKey: nil, Path: []Dependency{}, Cause: cause}
+	}
+	return f.parent.newErrorFor(f.key, err, cause)
+}
+
+func (f *resolveFrame) getOrCreateCache(
+	owner *registry,
+	key any,
+	policy CachePolicy,
+	disposable bool,
+	create func(ctx resolveContext) (any, error),
+) (any, error) {
+	f.policy = policy
+	if err := f.checkCaptive(); err != nil {
+		return nil, err
+	}
+	frame := f
+	if policy == GlobalCache {
+		// the instance is shared within the container where the registration is made,
+		// so its dependencies are resolved within that container, not within the child containers
+		if context := f.context.ownerOf(owner); context != f.context {
+			frame = &resolveFrame{
+				context:    context,
+				parent:     f.parent,
+				activation: f.activation,
+				key:        f.key,
+				policy:     f.policy,
+				ctx:        f.ctx,
+				binding:    f.binding,
+				finished:   0,
+			}
+		}
+		// the scopes opened with DefaultCacheMode are not closed with the container, but the cache of
+		// the container has been disposed, so the instances created in it would never be disposed
+		if frame.context.ownerOf(owner).container.isClosed() {
+			return nil, f.newError(ErrScopeClosed, nil)
+		}
+	}
+	ret, err := frame.context.getOrCreateInstance(owner, key, f, policy, disposable, func() (any, error) {
+		// the instance may outlive the scope of the resolution, e.g. the singletons resolved within a scope,
+		// so the Lazy and the factory functions injected into it are bound to the owner of the cache
+		bound := frame.bind(frame.context.cacheOwnerOf(policy))
+		defer bound.finish()
+		return create(bound)
+	})
+	if err == errCacheWaitCycle { //nolint:errorlint
+		// the instance is being created by another goroutine, which is waiting for this activation
+		return nil, f.newError(ErrCircularDependency, err)
+	}
+	return ret, err
+}
+
+func (f *resolveFrame) finish() {
+	atomic.StoreInt32(&f.finished, 1)
+}
+
+func (f *resolveFrame) isFinished() bool {
+	return atomic.LoadInt32(&f.finished) != 0
+}
+
+// Returns the frame whose deferred resolutions are bound to the context, or this frame if it is nil or unchanged.
+func (f *resolveFrame) bind(context *defaultContext) *resolveFrame {
+	if context == nil || context == f.binding {
+		return f
+	}
+	frame := *f
+	frame.binding = context
+	return &frame
+}
+
+func (f *resolveFrame) withContext(ctx context.Context) resolveContext {
+	frame := *f
+	frame.ctx = ctx
+	return &frame
+}
+
+func (f *resolveFrame) resolveAll(key registryKey) (any, error) {
+	tkey := registryKey{serviceType: key.serviceType.Elem(), serviceKey: key.serviceKey}
+	entries := f.context.lookup(tkey)
+	num := len(entries)
+	if num == 0 {
+		return nil, f.parent.newErrorFor(key, ErrNotRegistered, nil)
+	}
+	// resolve all
+	instances := reflect.MakeSlice(key.serviceType, num, num)
+	for i, entry := range entries {
+		frame, err := f.push(tkey)
+		if err != nil {
+			return nil, err
+		}
+		instance, err := entry.activate(frame)
+		if err != nil {
+			return nil, err
+		}
+		instances.Index(i).Set(reflect.ValueOf(instance))
+	}
+	return instances.Interface(), nil
+}
+
+func (f *resolveFrame) resolve(key registryKey) (any, error) {
+	frame, err := f.push(key)
+	if err != nil {
+		return nil, err
+	}
+	// look up entry with key
+	entries := f.context.lookup(key)
+	if len(entries) == 0 {
+		// if service type is []T, look up with T
+		if key.serviceType.Kind() == reflect.Slice {
+			return frame.resolveAll(key)
+		}
+		// if service type is Lazy[T] or func() (T, error), resolve T later
+		if elemKey, ok := lazyElemKey(key); ok {
+			return frame.resolveLazy(key, elemKey)
+		}
+		if elemKey, ok := factoryElemKey(key); ok {
+			return frame.resolveFactory(key, elemKey)
+		}
+		// if service type is Optional[T], resolve T if registered
+		if elemKey, ok := optionalElemKey(key); ok {
+			return frame.resolveOptionalWrapper(key, elemKey)
+		}
+		// if service type is context.Context, inject the context of the resolution
+		if isContextKey(key) {
+			return frame.goContext(), nil
+		}
+		return nil, f.newErrorFor(key, ErrNotRegistered, nil)
+	}
+	// resolve one
+	if len(entries) > 1 {
+		return nil, f.newErrorFor(key, ErrAmbiguous, nil)
+	}
+	return entries[0].activate(frame)
+}
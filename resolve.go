@@ -1,12 +1,12 @@
 package manioc
 
 import (
-	"errors"
+	"context"
 )
 
 func mergeResolveOptions(opts []ResolveOption) *resolveOptions {
 	options := &resolveOptions{
-		scope: globalContainer,
+		scope: GlobalContainer(),
 		key:   nil,
 	}
 	for _, opt := range opts {
@@ -16,23 +16,7 @@ func mergeResolveOptions(opts []ResolveOption) *resolveOptions {
 }
 
 func Resolve[T any](opts ...ResolveOption) (T, error) {
-	// parse option
-	options := mergeResolveOptions(opts)
-	// get context
-	ctx := options.scope.getResolveContext()
-	if ctx == nil {
-		return *new(T), errors.New("the scope has been closed")
-	}
-	// resolve
-	instance, err := ctx.resolve(registryKey{
-		serviceType: typeof[T](),
-		serviceKey:  options.key,
-	})
-	if err != nil {
-		return *new(T), err
-	}
-	//nolint:forcetypeassert
-	return instance.(T), nil
+	return ResolveContext[T](context.Background(), opts...)
 }
 
 func directResolve(activator activator, opts ...ResolveOption) (any, error) {
@@ -40,6 +24,9 @@ func directResolve(activator activator, opts ...ResolveOption) (any, error) {
 	options := mergeResolveOptions(opts)
 	// get context
 	ctx := options.scope.getResolveContext()
+	if ctx == nil {
+		return nil, newScopeClosedError(nil, nil)
+	}
 	// install field injection activator
 	activator = &fieldInjectionActivator{baseActivator: activator}
 	// resolve
@@ -66,7 +53,7 @@ func ResolveInstance[T any](instance T, opts ...ResolveOption) (T, error) {
 
 func ResolveFunction[T any, TFunction any](fun TFunction, opts ...ResolveOption) (T, error) {
 	// using constructor activator
-	activator, err := newConstructorActivator[T](fun)
+	activator, err := newConstructorActivator[T](fun, map[int]any{})
 	if err != nil {
 		return *new(T), err
 	}
@@ -0,0 +1,99 @@
+package manioc
+
+import (
+	"context"
+	"time"
+)
+
+type scopeContextKey struct{}
+
+// ScopeFromContext returns the scope carried by the context.Context.
+// The context.Context injected into constructors and fields carries the scope
+// which owns the instance, e.g. the container for the instances cached with GlobalCache policy.
+func ScopeFromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeContextKey{}).(Scope)
+	return scope, ok
+}
+
+// ContextWithScope returns a copy of the context.Context which carries the scope.
+// ResolveContext resolves within the scope carried by the context.Context by default.
+func ContextWithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeContextKey{}, scope)
+}
+
+// Returns true if the service type is context.Context.
+func isContextKey(key registryKey) bool {
+	return key.serviceType == typeof[context.Context]()
+}
+
+// detachedContext carries the values of the parent context.Context, but it is never canceled.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (c detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (c detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key any) any {
+	return c.parent.Value(key)
+}
+
+// Returns the context.Context to be injected, which carries the scope which owns the instance being activated.
+// The instance may outlive the scope of the resolution, e.g. the singletons resolved within a scope,
+// so the scope is the one which the frame is bound to, i.e. the owner of the cache.
+// If the instance, or the one it is injected into, is cached, the context.Context is detached from
+// the cancellation of the resolution, since the instance outlives the resolution.
+func (f *resolveFrame) goContext() context.Context {
+	ctx := f.ctx
+	for frame := f.parent; frame != nil; frame = frame.parent {
+		if frame.policy != NeverCache {
+			ctx = detachedContext{parent: ctx}
+			break
+		}
+	}
+	return ContextWithScope(ctx, f.binding.scope)
+}
+
+// ResolveContext resolves T with the context.Context. The context.Context is injected into
+// the constructors and fields that require context.Context, and the resolution fails with
+// ErrCanceled if the context.Context is canceled before an instance is activated.
+// The instances cached with GlobalCache or ScopedCache policy, and their dependencies, outlive the resolution,
+// so the context.Context injected into them carries the values of the given one, but it is never canceled.
+// If the WithScope option is not specified, the scope carried by the context.Context is used,
+// or the global container if it does not carry any scope.
+func ResolveContext[T any](ctx context.Context, opts ...ResolveOption) (T, error) {
+	// parse option
+	if scope, ok := ScopeFromContext(ctx); ok {
+		opts = append([]ResolveOption{WithScope(scope)}, opts...)
+	}
+	options := mergeResolveOptions(opts)
+	// get context
+	rctx := options.scope.getResolveContext()
+	if rctx == nil {
+		return *new(T), newScopeClosedError(typeof[T](), options.key)
+	}
+	// resolve
+	instance, err := rctx.withContext(ctx).resolve(registryKey{
+		serviceType: typeof[T](),
+		serviceKey:  options.key,
+	})
+	if err != nil {
+		return *new(T), err
+	}
+	//nolint:forcetypeassert
+	return instance.(T), nil
+}
+
+// ResolveManyContext is a variant of ResolveMany with the context.Context. See ResolveContext for details.
+func ResolveManyContext[T any](ctx context.Context, opts ...ResolveOption) ([]T, error) {
+	return ResolveContext[[]T](ctx, opts...)
+}
@@ -1,11 +1,31 @@
 package manioc
 
+import (
+	"sync"
+)
+
 type defaultScope struct {
+	mu          sync.RWMutex
 	context     *defaultContext
 	childScopes []Scope
+	// removes this scope from the child scopes of the parent, or nil if it is not registered into the parent
+	detach func()
+	// false if the scoped cache is shared with the parent scope (SyncCacheMode)
+	ownsCache bool
+}
+
+func (c *defaultScope) getScopedRegisterContext() scopedRegisterContext {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.context == nil {
+		return nil
+	}
+	return c.context
 }
 
 func (c *defaultScope) getResolveContext() resolveContext {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	if c.context == nil {
 		return nil
 	}
@@ -24,41 +44,115 @@ func (c *defaultScope) OpenScope(opts ...OpenScopeOption) (Scope, func()) {
 	for _, opt := range opts {
 		opt.apply(options)
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	// create new scope
 	ret := &defaultScope{
-		context: &defaultContext{
-			registry:    c.context.registry,
-			globalCache: c.context.globalCache,
-			scopedCache: make(map[any]any),
-		},
+		context:     nil,
 		childScopes: make([]Scope, 0),
+		detach:      nil,
+		ownsCache:   true,
+	}
+	if c.context == nil {
+		// the parent scope has been closed, so the new scope is also closed
+		return ret, func() {}
 	}
+	ret.context = &defaultContext{
+		parent:         c.context.parent,
+		container:      c.context.container,
+		cacheOwner:     nil,
+		scope:          ret,
+		registry:       c.context.registry,
+		instances:      newRegistry(nil),
+		globalCache:    c.context.globalCache,
+		scopedCache:    newInstanceCache(),
+		closed:         0,
+		captiveMode:    c.context.captiveMode,
+		conflictPolicy: c.context.conflictPolicy,
+	}
+	ret.context.cacheOwner = ret.context
 	if options.cacheMode == InheritCacheMode {
 		// inherit parent cache
-		for k, v := range c.context.scopedCache {
-			ret.context.scopedCache[k] = v
-		}
+		ret.context.scopedCache = c.context.scopedCache.clone()
+		ret.context.instances = newRegistry(c.context.instances)
 		// register child scope into parent
-		c.childScopes = append(c.childScopes, ret)
+		ret.detach = c.addChild(ret)
 	} else if options.cacheMode == SyncCacheMode {
 		// syncrhonize cache
 		ret.context.scopedCache = c.context.scopedCache
+		ret.context.cacheOwner = c.context.cacheOwner
+		// the instances registered for this scope are not visible to the parent
+		ret.context.instances = newRegistry(c.context.instances)
+		ret.ownsCache = false
 		// register child scope into parent
-		c.childScopes = append(c.childScopes, ret)
+		ret.detach = c.addChild(ret)
 	}
 	cleanup := func() {
 		// after this function is called, this scope is no longer available.
-		ret.closeScope()
+		// use Close instead to handle the errors on disposal.
+		_ = ret.Close()
 	}
 	return ret, cleanup
 }
 
-func (c *defaultScope) closeScope() {
-	if c.childScopes != nil {
-		for _, scope := range c.childScopes {
-			scope.closeScope()
+// Register the child scope to close it with this scope, and returns the function to remove it from this scope.
+// The caller must hold the lock.
+func (c *defaultScope) addChild(child Scope) func() {
+	c.childScopes = append(c.childScopes, child)
+	return func() { c.removeChild(child) }
+}
+
+// Remove the closed child scope, so that the child scopes do not accumulate, e.g. the ones opened per request.
+func (c *defaultScope) removeChild(child Scope) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, scope := range c.childScopes {
+		if scope == child {
+			last := len(c.childScopes) - 1
+			copy(c.childScopes[i:], c.childScopes[i+1:])
+			// release the reference to the removed scope
+			c.childScopes[last] = nil
+			c.childScopes = c.childScopes[:last]
+			return
+		}
+	}
+}
+
+func (c *defaultScope) Close() error {
+	return c.closeScope()
+}
+
+func (c *defaultScope) closeScope() error {
+	c.mu.Lock()
+	context := c.context
+	childScopes := c.childScopes
+	detach := c.detach
+	c.childScopes = nil
+	c.detach = nil
+	c.context = nil
+	c.mu.Unlock()
+	if context == nil {
+		// already closed
+		return nil
+	}
+	context.close()
+	if detach != nil {
+		detach()
+	}
+	errs := make([]error, 0)
+	// close child scopes first, since their instances may depend on the instances of this scope
+	for _, scope := range childScopes {
+		if err := scope.closeScope(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.ownsCache {
+		if err := context.scopedCache.dispose(); err != nil {
+			errs = append(errs, err)
 		}
-		c.childScopes = nil
-		c.context = nil
+	} else {
+		// the cache is shared with the parent, so discard only the instances registered for this scope
+		context.scopedCache.remove(context.scopedInstanceKeys())
 	}
+	return newAggregateError(errs)
 }
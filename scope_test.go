@@ -0,0 +1,45 @@
+package manioc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_closeScope_DetachFromParent(t *testing.T) {
+	modes := map[string]ScopeCacheMode{"InheritCacheMode": InheritCacheMode, "SyncCacheMode": SyncCacheMode}
+	for name, mode := range modes {
+		mode := mode
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			ctr := newDefaultContainer(&containerOptions{captiveMode: DefaultCaptiveMode, conflictPolicy: Append})
+
+			// the closed child scopes are removed from the parent, e.g. the ones opened per request
+			for i := 0; i < 1000; i++ {
+				_, closeScope := ctr.OpenScope(WithCacheMode(mode))
+				closeScope()
+			}
+			assert.Empty(ctr.childScopes)
+
+			// the open ones are kept in order to close them with the parent
+			scope1, _ := ctr.OpenScope(WithCacheMode(mode))
+			scope2, closeScope2 := ctr.OpenScope(WithCacheMode(mode))
+			scope3, _ := ctr.OpenScope(WithCacheMode(mode))
+			closeScope2()
+			assert.Equal([]Scope{scope1, scope3}, ctr.childScopes)
+			assert.Nil(scope2.getResolveContext())
+			assert.Nil(ctr.Close())
+			assert.Nil(scope1.getResolveContext())
+			assert.Nil(scope3.getResolveContext())
+		})
+	}
+
+	t.Run("child containers", func(t *testing.T) {
+		assert := assert.New(t)
+		ctr := newDefaultContainer(&containerOptions{captiveMode: DefaultCaptiveMode, conflictPolicy: Append})
+		for i := 0; i < 1000; i++ {
+			assert.Nil(ctr.NewChild().Close())
+		}
+		assert.Empty(ctr.childScopes)
+	})
+}
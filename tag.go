@@ -7,15 +7,17 @@ import (
 )
 
 type tagInfo struct {
-	inject bool
-	key    any
+	inject   bool
+	key      any
+	optional bool
 }
 
 func parseTag(tag reflect.StructTag) (*tagInfo, error) {
-	// example; manioc:"inject,key=foo"
+	// example; manioc:"inject,key=foo,optional"
 	info := &tagInfo{
-		inject: false,
-		key:    nil,
+		inject:   false,
+		key:      nil,
+		optional: false,
 	}
 	str := tag.Get("manioc")
 	for _, part := range strings.Split(str, ",") {
@@ -26,6 +28,10 @@ func parseTag(tag reflect.StructTag) (*tagInfo, error) {
 			info.inject = true
 			continue
 		}
+		if part == "optional" {
+			info.optional = true
+			continue
+		}
 		if strings.HasPrefix(part, "key=") {
 			// if the value part is empty, remain key as nil
 			if len(part) > len("key=") {
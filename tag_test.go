@@ -77,6 +77,31 @@ func Test_parseTag_Key(t *testing.T) {
 	})
 }
 
+func Test_parseTag_Optional(t *testing.T) {
+	t.Run("manioc: optional", func(t *testing.T) {
+		var data struct {
+			value any `manioc:"optional"`
+		}
+		assertParseTagResult(t, data, 0, &tagInfo{inject: false, key: nil, optional: true})
+	})
+
+	t.Run("optional with inject and key", func(t *testing.T) {
+		var data struct {
+			value0 any `manioc:"inject,optional"`
+			value1 any `manioc:"optional,key=foo,inject"`
+		}
+		assertParseTagResult(t, data, 0, &tagInfo{inject: true, key: nil, optional: true})
+		assertParseTagResult(t, data, 1, &tagInfo{inject: true, key: "foo", optional: true})
+	})
+
+	t.Run("optional does not take value", func(t *testing.T) {
+		var data struct {
+			value any `manioc:"optional=true"`
+		}
+		assertParseTagError(t, data, 0)
+	})
+}
+
 func Test_parseTag_Combinations(t *testing.T) {
 	t.Run("valid tags", func(t *testing.T) {
 		var data struct {
@@ -0,0 +1,63 @@
+package manioc_benchmarks_test
+
+import (
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_Allocs checks the number of allocations of the resolution paths, to catch the performance regressions.
+// The budgets are slightly larger than the actual numbers, so update them when the paths are improved.
+func Test_Allocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the number of allocations is not stable with the race detector")
+	}
+	tests := []struct {
+		name   string
+		policy manioc.CachePolicy
+		fn     func(t *testing.T, ctr manioc.Container)
+		budget float64
+	}{
+		{"Resolve/NeverCache", manioc.NeverCache, resolveAllocs[*Leaf], 6},
+		{"Resolve/ScopedCache", manioc.ScopedCache, resolveAllocs[*Leaf], 6},
+		{"Resolve/GlobalCache", manioc.GlobalCache, resolveAllocs[*Leaf], 6},
+		{"ResolveMany/NeverCache", manioc.NeverCache, resolveManyAllocs, 26},
+		{"DeepConstructors/NeverCache", manioc.NeverCache, resolveAllocs[*Chain5], 30},
+		{"DeepConstructors/GlobalCache", manioc.GlobalCache, resolveAllocs[*Chain5], 6},
+		{"DeepFields/NeverCache", manioc.NeverCache, resolveAllocs[*Deep], 36},
+		{"WideFields/NeverCache", manioc.NeverCache, resolveAllocs[*Wide], 50},
+		{"OpenScope/DefaultCacheMode", manioc.ScopedCache, openScopeAllocs(manioc.DefaultCacheMode), 34},
+		{"OpenScope/InheritCacheMode", manioc.ScopedCache, openScopeAllocs(manioc.InheritCacheMode), 40},
+		{"OpenScope/SyncCacheMode", manioc.ScopedCache, openScopeAllocs(manioc.SyncCacheMode), 20},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctr := newContainer(t, tt.policy)
+			allocs := testing.AllocsPerRun(100, func() { tt.fn(t, ctr) })
+			assert.LessOrEqual(t, allocs, tt.budget)
+		})
+	}
+}
+
+func resolveAllocs[T any](t *testing.T, ctr manioc.Container) {
+	t.Helper()
+	resolve[T](t, ctr)
+}
+
+func resolveManyAllocs(t *testing.T, ctr manioc.Container) {
+	t.Helper()
+	if _, err := manioc.ResolveMany[IPlugin](manioc.WithScope(ctr)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func openScopeAllocs(mode manioc.ScopeCacheMode) func(t *testing.T, ctr manioc.Container) {
+	return func(t *testing.T, ctr manioc.Container) {
+		t.Helper()
+		scope, closeScope := ctr.OpenScope(manioc.WithCacheMode(mode))
+		resolve[*Chain1](t, scope)
+		closeScope()
+	}
+}
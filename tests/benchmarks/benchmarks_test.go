@@ -0,0 +1,208 @@
+package manioc_benchmarks_test
+
+import (
+	"testing"
+
+	"github.com/fuzmish/manioc"
+)
+
+// the services with constructor injection, forming a chain of dependencies
+type (
+	Leaf   struct{}
+	Chain1 struct{ next *Leaf }
+	Chain2 struct{ next *Chain1 }
+	Chain3 struct{ next *Chain2 }
+	Chain4 struct{ next *Chain3 }
+	Chain5 struct{ next *Chain4 }
+)
+
+func NewChain1(next *Leaf) *Chain1   { return &Chain1{next: next} }
+func NewChain2(next *Chain1) *Chain2 { return &Chain2{next: next} }
+func NewChain3(next *Chain2) *Chain3 { return &Chain3{next: next} }
+func NewChain4(next *Chain3) *Chain4 { return &Chain4{next: next} }
+func NewChain5(next *Chain4) *Chain5 { return &Chain5{next: next} }
+
+// the services with field injection, forming a chain of dependencies
+type Node[T any] struct {
+	next  T     `manioc:"inject"`
+	leaf  *Leaf `manioc:"inject"`
+	value int
+}
+
+type Deep = Node[*Node[*Node[*Node[*Node[*Leaf]]]]]
+
+// the service with many fields to inject
+type Wide struct {
+	f0 *Leaf   `manioc:"inject"`
+	f1 *Chain1 `manioc:"inject"`
+	f2 *Leaf   `manioc:"inject"`
+	f3 *Chain1 `manioc:"inject"`
+	f4 *Leaf   `manioc:"inject"`
+	f5 *Chain1 `manioc:"inject"`
+	f6 *Leaf   `manioc:"inject"`
+	f7 *Chain1 `manioc:"inject"`
+	f8 IPlugin `manioc:"inject,key=missing,optional"`
+	f9 int
+}
+
+type IPlugin interface {
+	Name() string
+}
+
+type Plugin struct{}
+
+func (p *Plugin) Name() string { return "plugin" }
+
+func must(b testing.TB, errs ...error) {
+	b.Helper()
+	for _, err := range errs {
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func newContainer(b testing.TB, policy manioc.CachePolicy) manioc.Container {
+	b.Helper()
+	ctr := manioc.NewContainer()
+	opts := []manioc.RegisterOption{manioc.WithContainer(ctr), manioc.WithCachePolicy(policy)}
+	must(b,
+		manioc.Register[*Leaf, *Leaf](opts...),
+		manioc.RegisterConstructor[*Chain1](NewChain1, opts...),
+		manioc.RegisterConstructor[*Chain2](NewChain2, opts...),
+		manioc.RegisterConstructor[*Chain3](NewChain3, opts...),
+		manioc.RegisterConstructor[*Chain4](NewChain4, opts...),
+		manioc.RegisterConstructor[*Chain5](NewChain5, opts...),
+		manioc.Register[*Node[*Leaf], *Node[*Leaf]](opts...),
+		manioc.Register[*Node[*Node[*Leaf]], *Node[*Node[*Leaf]]](opts...),
+		manioc.Register[*Node[*Node[*Node[*Leaf]]], *Node[*Node[*Node[*Leaf]]]](opts...),
+		manioc.Register[*Node[*Node[*Node[*Node[*Leaf]]]], *Node[*Node[*Node[*Node[*Leaf]]]]](opts...),
+		manioc.Register[*Deep, *Deep](opts...),
+		manioc.Register[*Wide, *Wide](opts...),
+	)
+	for i := 0; i < 8; i++ {
+		must(b, manioc.Register[IPlugin, Plugin](opts...))
+	}
+	return ctr
+}
+
+//nolint:gochecknoglobals
+var policies = []manioc.CachePolicy{manioc.NeverCache, manioc.ScopedCache, manioc.GlobalCache}
+
+//nolint:gochecknoglobals
+var cacheModes = []struct {
+	name string
+	mode manioc.ScopeCacheMode
+}{
+	{name: "DefaultCacheMode", mode: manioc.DefaultCacheMode},
+	{name: "InheritCacheMode", mode: manioc.InheritCacheMode},
+	{name: "SyncCacheMode", mode: manioc.SyncCacheMode},
+}
+
+func resolve[T any](b testing.TB, scope manioc.Scope) {
+	if _, err := manioc.Resolve[T](manioc.WithScope(scope)); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func Benchmark_Resolve(b *testing.B) {
+	for _, policy := range policies {
+		b.Run(policy.String(), func(b *testing.B) {
+			ctr := newContainer(b, policy)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resolve[*Leaf](b, ctr)
+			}
+		})
+	}
+}
+
+func Benchmark_ResolveMany(b *testing.B) {
+	for _, policy := range policies {
+		b.Run(policy.String(), func(b *testing.B) {
+			ctr := newContainer(b, policy)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := manioc.ResolveMany[IPlugin](manioc.WithScope(ctr)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func Benchmark_Resolve_DeepConstructors(b *testing.B) {
+	for _, policy := range policies {
+		b.Run(policy.String(), func(b *testing.B) {
+			ctr := newContainer(b, policy)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resolve[*Chain5](b, ctr)
+			}
+		})
+	}
+}
+
+func Benchmark_Resolve_DeepFields(b *testing.B) {
+	for _, policy := range policies {
+		b.Run(policy.String(), func(b *testing.B) {
+			ctr := newContainer(b, policy)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resolve[*Deep](b, ctr)
+			}
+		})
+	}
+}
+
+func Benchmark_Resolve_WideFields(b *testing.B) {
+	for _, policy := range policies {
+		b.Run(policy.String(), func(b *testing.B) {
+			ctr := newContainer(b, policy)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resolve[*Wide](b, ctr)
+			}
+		})
+	}
+}
+
+func Benchmark_OpenScope(b *testing.B) {
+	for _, mode := range cacheModes {
+		mode := mode
+		b.Run(mode.name, func(b *testing.B) {
+			ctr := newContainer(b, manioc.ScopedCache)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				scope, closeScope := ctr.OpenScope(manioc.WithCacheMode(mode.mode))
+				resolve[*Chain1](b, scope)
+				closeScope()
+			}
+		})
+	}
+}
+
+func Benchmark_Resolve_Parallel(b *testing.B) {
+	for _, policy := range policies {
+		b.Run(policy.String(), func(b *testing.B) {
+			ctr := newContainer(b, policy)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					// b.Fatal must not be called from the goroutines running in parallel
+					if _, err := manioc.Resolve[*Chain5](manioc.WithScope(ctr)); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
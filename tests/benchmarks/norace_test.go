@@ -0,0 +1,5 @@
+//go:build !race
+
+package manioc_benchmarks_test
+
+const raceEnabled = false
@@ -0,0 +1,6 @@
+//go:build race
+
+package manioc_benchmarks_test
+
+// the race detector changes the number of allocations
+const raceEnabled = true
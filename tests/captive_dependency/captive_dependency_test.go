@@ -0,0 +1,194 @@
+package manioc_captive_dependency_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IFooService interface {
+	doFoo()
+}
+
+// FooService implements IFooService
+type FooService struct {
+	Value int
+}
+
+func (s *FooService) doFoo() {}
+
+type IBarService interface {
+	doBar()
+}
+
+// BarService implements IBarService, and requires IFooService
+type BarService struct {
+	foo IFooService `manioc:"inject"`
+}
+
+func (s *BarService) doBar() {}
+
+type IBazService interface {
+	doBaz()
+}
+
+// BazService implements IBazService, and requires IBarService
+type BazService struct {
+	bar IBarService `manioc:"inject"`
+}
+
+func (s *BazService) doBaz() {}
+
+// LazyBazService implements IBazService, and requires IBarService lazily
+type LazyBazService struct {
+	bar manioc.Lazy[IBarService] `manioc:"inject"`
+}
+
+func (s *LazyBazService) doBaz() {}
+
+func setup(
+	t *testing.T,
+	mode manioc.CaptiveMode,
+	foo manioc.CachePolicy,
+	bar manioc.CachePolicy,
+	baz manioc.CachePolicy,
+) manioc.Container {
+	t.Helper()
+	ctr := manioc.NewContainer(manioc.WithCaptiveMode(mode))
+	assert.Nil(t, manioc.Register[IFooService, FooService](manioc.WithContainer(ctr), manioc.WithCachePolicy(foo)))
+	assert.Nil(t, manioc.Register[IBarService, BarService](manioc.WithContainer(ctr), manioc.WithCachePolicy(bar)))
+	assert.Nil(t, manioc.Register[IBazService, BazService](manioc.WithContainer(ctr), manioc.WithCachePolicy(baz)))
+	return ctr
+}
+
+func Test_CaptiveDependency(t *testing.T) {
+	G, S, N := manioc.GlobalCache, manioc.ScopedCache, manioc.NeverCache
+	cases := map[string]struct {
+		mode          manioc.CaptiveMode
+		foo, bar, baz manioc.CachePolicy
+		// the expected error on resolving IBazService, or empty
+		expected string
+	}{
+		"default: scoped captured by global": {
+			mode: manioc.DefaultCaptiveMode, foo: N, bar: S, baz: G,
+			expected: "captive dependency detected: manioc_captive_dependency_test.IBazService -> " +
+				"manioc_captive_dependency_test.IBarService: " +
+				"`manioc_captive_dependency_test.IBarService` (ScopedCache) is captured by " +
+				"`manioc_captive_dependency_test.IBazService` (GlobalCache)",
+		},
+		"default: scoped captured by global via transient": {
+			mode: manioc.DefaultCaptiveMode, foo: S, bar: N, baz: G,
+			expected: "captive dependency detected: manioc_captive_dependency_test.IBazService -> " +
+				"manioc_captive_dependency_test.IBarService -> manioc_captive_dependency_test.IFooService: " +
+				"`manioc_captive_dependency_test.IFooService` (ScopedCache) is captured by " +
+				"`manioc_captive_dependency_test.IBazService` (GlobalCache)",
+		},
+		"default: transient captured by global is allowed": {
+			mode: manioc.DefaultCaptiveMode, foo: N, bar: N, baz: G,
+		},
+		"default: global captured by scoped is allowed": {
+			mode: manioc.DefaultCaptiveMode, foo: G, bar: S, baz: N,
+		},
+		"strict: transient captured by global": {
+			mode: manioc.StrictCaptiveMode, foo: N, bar: N, baz: G,
+			expected: "captive dependency detected: manioc_captive_dependency_test.IBazService -> " +
+				"manioc_captive_dependency_test.IBarService: " +
+				"`manioc_captive_dependency_test.IBarService` (NeverCache) is captured by " +
+				"`manioc_captive_dependency_test.IBazService` (GlobalCache)",
+		},
+		"strict: transient captured by scoped": {
+			mode: manioc.StrictCaptiveMode, foo: N, bar: S, baz: S,
+			expected: "captive dependency detected: manioc_captive_dependency_test.IBazService -> " +
+				"manioc_captive_dependency_test.IBarService -> manioc_captive_dependency_test.IFooService: " +
+				"`manioc_captive_dependency_test.IFooService` (NeverCache) is captured by " +
+				"`manioc_captive_dependency_test.IBarService` (ScopedCache)",
+		},
+		"strict: same or longer lifetimes are allowed": {
+			mode: manioc.StrictCaptiveMode, foo: G, bar: S, baz: N,
+		},
+		"ignore: scoped captured by global is allowed": {
+			mode: manioc.IgnoreCaptiveMode, foo: S, bar: S, baz: G,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			ctr := setup(t, c.mode, c.foo, c.bar, c.baz)
+			scope, closeScope := ctr.OpenScope()
+			defer closeScope()
+
+			ret, err := manioc.Resolve[IBazService](manioc.WithScope(scope))
+			validateErr := manioc.Validate(ctr)
+			if c.expected == "" {
+				assert.Nil(err)
+				assert.NotNil(ret)
+				assert.Nil(validateErr)
+				return
+			}
+			assert.Nil(ret)
+			assert.ErrorIs(err, manioc.ErrCaptiveDependency)
+			assert.EqualError(err, c.expected)
+			// the same captive dependency is reported by the validation,
+			// where the path starts from the captor
+			assert.ErrorIs(validateErr, manioc.ErrCaptiveDependency)
+			var aggregated *manioc.AggregateError
+			assert.ErrorAs(validateErr, &aggregated)
+			assert.Len(aggregated.Errors, 1)
+			cause := c.expected[strings.Index(c.expected, ": `"):]
+			assert.True(strings.HasSuffix(aggregated.Errors[0].Error(), cause), aggregated.Errors[0].Error())
+		})
+	}
+}
+
+func Test_CaptiveDependency_Lazy(t *testing.T) {
+	assert := assert.New(t)
+
+	// lazy resolutions start new dependency chains, so they are not regarded as captive
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IFooService, FooService](
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.ScopedCache),
+	))
+	assert.Nil(manioc.Register[IBarService, BarService](
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.ScopedCache),
+	))
+	assert.Nil(manioc.Register[IBazService, LazyBazService](
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.GlobalCache),
+	))
+	assert.Nil(manioc.Validate(ctr))
+
+	baz, err := manioc.Resolve[IBazService](manioc.WithScope(ctr))
+	assert.Nil(err)
+	bar, err := baz.(*LazyBazService).bar.Get()
+	assert.Nil(err)
+	assert.NotNil(bar)
+}
+
+func Test_CaptiveDependency_ResolveMany(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IFooService, FooService](
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.ScopedCache),
+	))
+	assert.Nil(manioc.RegisterConstructor[IBarService](
+		func(foo []IFooService) *BarService { return &BarService{foo: foo[0]} },
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.GlobalCache),
+	))
+
+	_, err := manioc.Resolve[IBarService](manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrCaptiveDependency)
+	assert.ErrorIs(manioc.Validate(ctr), manioc.ErrCaptiveDependency)
+}
+
+func Test_CaptiveDependency_InvalidMode(t *testing.T) {
+	assert.Panics(t, func() { manioc.WithCaptiveMode(manioc.CaptiveMode(-1)) })
+}
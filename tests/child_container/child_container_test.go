@@ -0,0 +1,224 @@
+package manioc_child_container_test
+
+import (
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IFooService interface {
+	doFoo()
+}
+
+// FooService1 implements IFooService
+type FooService1 struct {
+	Value int
+}
+
+func (s *FooService1) doFoo() {}
+
+// FooService2 implements IFooService
+type FooService2 struct {
+	Value int
+}
+
+func (s *FooService2) doFoo() {}
+
+type IBarService interface {
+	doBar()
+}
+
+// BarService implements IBarService
+type BarService struct {
+	foo IFooService `manioc:"inject"`
+}
+
+func (s *BarService) doBar() {}
+
+// LoggingFooService decorates IFooService
+type LoggingFooService struct {
+	inner IFooService
+	tag   string
+}
+
+func (s *LoggingFooService) doFoo() {}
+
+func Test_ChildContainer(t *testing.T) {
+	t.Run("inherit registrations", func(t *testing.T) {
+		assert := assert.New(t)
+
+		parent := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService1](manioc.WithContainer(parent)))
+		child := parent.NewChild()
+
+		// registrations in the parent are visible from the child, even if registered later
+		assert.Nil(manioc.Register[IBarService, BarService](manioc.WithContainer(parent)))
+		assert.True(manioc.IsRegistered[IFooService](manioc.WithContainer(child)))
+		ret, err := manioc.Resolve[IBarService](manioc.WithScope(child))
+		assert.Nil(err)
+		_, ok := ret.(*BarService).foo.(*FooService1)
+		assert.True(ok)
+		assert.Len(manioc.Registrations(child), 2)
+		assert.Nil(manioc.Validate(child))
+	})
+
+	t.Run("override registrations", func(t *testing.T) {
+		assert := assert.New(t)
+
+		parent := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService1](manioc.WithContainer(parent)))
+		assert.Nil(manioc.Register[IBarService, BarService](manioc.WithContainer(parent)))
+		child := parent.NewChild()
+		assert.Nil(manioc.Register[IFooService, FooService2](manioc.WithContainer(child)))
+
+		// the dependencies of the inherited registrations are resolved within the child
+		ret, err := manioc.Resolve[IBarService](manioc.WithScope(child))
+		assert.Nil(err)
+		_, ok := ret.(*BarService).foo.(*FooService2)
+		assert.True(ok)
+
+		// the overridden registration is not ambiguous
+		many, err := manioc.ResolveMany[IFooService](manioc.WithScope(child))
+		assert.Nil(err)
+		assert.Len(many, 1)
+		regs := manioc.Registrations(child)
+		assert.Len(regs, 2)
+
+		// the parent is not affected
+		ret, err = manioc.Resolve[IBarService](manioc.WithScope(parent))
+		assert.Nil(err)
+		_, ok = ret.(*BarService).foo.(*FooService1)
+		assert.True(ok)
+
+		// unregistration in the child only removes the local registrations
+		assert.True(manioc.Unregister[IFooService](manioc.WithContainer(child)))
+		assert.False(manioc.Unregister[IFooService](manioc.WithContainer(child)))
+		foo, err := manioc.Resolve[IFooService](manioc.WithScope(child))
+		assert.Nil(err)
+		_, ok = foo.(*FooService1)
+		assert.True(ok)
+	})
+
+	t.Run("share singletons", func(t *testing.T) {
+		assert := assert.New(t)
+
+		parent := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService1](
+			manioc.WithContainer(parent),
+			manioc.WithCachePolicy(manioc.GlobalCache),
+		))
+		assert.Nil(manioc.Register[IFooService, FooService2](
+			manioc.WithContainer(parent),
+			manioc.WithRegisterKey("local"),
+			manioc.WithCachePolicy(manioc.GlobalCache),
+		))
+		child1 := parent.NewChild()
+		child2 := parent.NewChild()
+		assert.Nil(manioc.Register[IFooService, FooService2](
+			manioc.WithContainer(child1),
+			manioc.WithRegisterKey("local"),
+			manioc.WithCachePolicy(manioc.GlobalCache),
+		))
+
+		// the inherited singletons are shared with the parent
+		fromChild := manioc.MustResolve[IFooService](manioc.WithScope(child1))
+		assert.Same(manioc.MustResolve[IFooService](manioc.WithScope(parent)), fromChild)
+		assert.Same(manioc.MustResolve[IFooService](manioc.WithScope(child2)), fromChild)
+
+		// the local singletons are not shared
+		local1 := manioc.MustResolve[IFooService](manioc.WithScope(child1), manioc.WithResolveKey("local"))
+		local2 := manioc.MustResolve[IFooService](manioc.WithScope(child2), manioc.WithResolveKey("local"))
+		assert.NotSame(local1, local2)
+		assert.Same(local2, manioc.MustResolve[IFooService](manioc.WithScope(parent), manioc.WithResolveKey("local")))
+
+		// scopes opened from the child also share the singletons
+		scope, closeScope := child1.OpenScope()
+		defer closeScope()
+		assert.Same(fromChild, manioc.MustResolve[IFooService](manioc.WithScope(scope)))
+		assert.Same(local1, manioc.MustResolve[IFooService](manioc.WithScope(scope), manioc.WithResolveKey("local")))
+	})
+
+	t.Run("inherited singletons are resolved within the parent", func(t *testing.T) {
+		assert := assert.New(t)
+
+		parent := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService1](manioc.WithContainer(parent)))
+		assert.Nil(manioc.Register[IBarService, BarService](
+			manioc.WithContainer(parent),
+			manioc.WithCachePolicy(manioc.GlobalCache),
+		))
+		child := parent.NewChild()
+		assert.Nil(manioc.Register[IFooService, FooService2](manioc.WithContainer(child)))
+
+		// the overridden registration in the child does not leak into the singleton of the parent
+		bar, ok := manioc.MustResolve[IBarService](manioc.WithScope(child)).(*BarService)
+		assert.True(ok)
+		_, ok = bar.foo.(*FooService1)
+		assert.True(ok)
+		assert.Same(bar, manioc.MustResolve[IBarService](manioc.WithScope(parent)))
+	})
+
+	t.Run("decorators", func(t *testing.T) {
+		assert := assert.New(t)
+
+		parent := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService1](
+			manioc.WithContainer(parent),
+			manioc.WithCachePolicy(manioc.GlobalCache),
+		))
+		assert.Nil(manioc.Decorate[IFooService](func(inner IFooService) IFooService {
+			return &LoggingFooService{inner: inner, tag: "parent"}
+		}, manioc.WithContainer(parent)))
+		child := parent.NewChild()
+		assert.Nil(manioc.Decorate[IFooService](func(inner IFooService) IFooService {
+			return &LoggingFooService{inner: inner, tag: "child"}
+		}, manioc.WithContainer(child)))
+
+		// the decorators of the child do not apply to the inherited registrations
+		foo, ok := manioc.MustResolve[IFooService](manioc.WithScope(child)).(*LoggingFooService)
+		assert.True(ok)
+		assert.Equal("parent", foo.tag)
+		_, ok = foo.inner.(*FooService1)
+		assert.True(ok)
+
+		// both decorators apply to the local registrations
+		assert.Nil(manioc.Register[IFooService, FooService2](manioc.WithContainer(child)))
+		foo, ok = manioc.MustResolve[IFooService](manioc.WithScope(child)).(*LoggingFooService)
+		assert.True(ok)
+		assert.Equal("child", foo.tag)
+		inner, ok := foo.inner.(*LoggingFooService)
+		assert.True(ok)
+		assert.Equal("parent", inner.tag)
+		_, ok = inner.inner.(*FooService2)
+		assert.True(ok)
+	})
+
+	t.Run("close with parent", func(t *testing.T) {
+		assert := assert.New(t)
+
+		parent := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService1](manioc.WithContainer(parent)))
+		child := parent.NewChild()
+		grandChild := child.NewChild()
+		_, err := manioc.Resolve[IFooService](manioc.WithScope(grandChild))
+		assert.Nil(err)
+
+		// closing the child does not affect the parent
+		assert.Nil(child.Close())
+		_, err = manioc.Resolve[IFooService](manioc.WithScope(grandChild))
+		assert.ErrorIs(err, manioc.ErrScopeClosed)
+		_, err = manioc.Resolve[IFooService](manioc.WithScope(parent))
+		assert.Nil(err)
+
+		// closing the parent closes the children
+		another := parent.NewChild()
+		assert.Nil(parent.Close())
+		_, err = manioc.Resolve[IFooService](manioc.WithScope(another))
+		assert.ErrorIs(err, manioc.ErrScopeClosed)
+
+		// children of a closed container are closed
+		_, err = manioc.Resolve[IFooService](manioc.WithScope(parent.NewChild()))
+		assert.ErrorIs(err, manioc.ErrScopeClosed)
+	})
+}
@@ -0,0 +1,105 @@
+package manioc_circular_dependency_test
+
+import (
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+// In this case, we will verify that circular dependencies are detected:
+//  A requires IB via constructor injection
+//  B implements IB
+//    B requires *A via field injection
+
+type A struct {
+	b IB
+}
+
+func NewA(b IB) *A {
+	return &A{b: b}
+}
+
+type IB interface {
+	doB()
+}
+
+type B struct {
+	a *A `manioc:"inject"`
+}
+
+func (b *B) doB() {}
+
+func Test_CircularDependency(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterConstructor[*A](NewA, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IB, B](manioc.WithContainer(ctr)))
+
+	// the resolution fails instead of overflowing the stack
+	_, err := manioc.Resolve[*A](manioc.WithScope(ctr))
+	assert.Error(err)
+	assert.Contains(
+		err.Error(),
+		"*manioc_circular_dependency_test.A -> manioc_circular_dependency_test.IB -> *manioc_circular_dependency_test.A",
+	)
+
+	_, err = manioc.Resolve[IB](manioc.WithScope(ctr))
+	assert.Error(err)
+	assert.Contains(
+		err.Error(),
+		"manioc_circular_dependency_test.IB -> *manioc_circular_dependency_test.A -> manioc_circular_dependency_test.IB",
+	)
+}
+
+type IKeyed interface {
+	doKeyed()
+}
+
+// Keyed requires IKeyed with key=next via field injection
+type Keyed struct {
+	next IKeyed `manioc:"inject,key=next"`
+}
+
+func (k *Keyed) doKeyed() {}
+
+func Test_CircularDependency_WithServiceKey(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IKeyed, Keyed](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IKeyed, Keyed](manioc.WithContainer(ctr), manioc.WithRegisterKey("next")))
+
+	// the same type with different keys is not a cycle by itself,
+	// but `next` requires itself
+	_, err := manioc.Resolve[IKeyed](manioc.WithScope(ctr))
+	assert.Error(err)
+	assert.Contains(err.Error(), "manioc_circular_dependency_test.IKeyed -> "+
+		"manioc_circular_dependency_test.IKeyed(key=next) -> "+
+		"manioc_circular_dependency_test.IKeyed(key=next)")
+}
+
+type IMany interface {
+	doMany()
+}
+
+// Many requires []IMany via field injection
+type Many struct {
+	all []IMany `manioc:"inject"`
+}
+
+func (m *Many) doMany() {}
+
+func Test_CircularDependency_ResolveMany(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterSingleton[IMany, Many](manioc.WithContainer(ctr)))
+
+	_, err := manioc.ResolveMany[IMany](manioc.WithScope(ctr))
+	assert.Error(err)
+	assert.Contains(err.Error(), "[]manioc_circular_dependency_test.IMany -> "+
+		"manioc_circular_dependency_test.IMany -> "+
+		"[]manioc_circular_dependency_test.IMany")
+}
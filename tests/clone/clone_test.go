@@ -0,0 +1,119 @@
+package manioc_clone_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IGreeter interface {
+	Greet() string
+}
+
+// Greeter implements IGreeter
+type Greeter struct {
+	name *string `manioc:"inject"`
+}
+
+func (g *Greeter) Greet() string {
+	return "hello " + *g.name
+}
+
+// ExclaimingGreeter decorates IGreeter
+type ExclaimingGreeter struct {
+	inner IGreeter
+}
+
+func (g *ExclaimingGreeter) Greet() string {
+	return g.inner.Greet() + "!"
+}
+
+func newName(name string) *string {
+	return &name
+}
+
+func Test_Clone(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(newName("foo"), manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterSingleton[IGreeter, Greeter](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Decorate[IGreeter](func(inner IGreeter) IGreeter {
+		return &ExclaimingGreeter{inner: inner}
+	}, manioc.WithContainer(ctr)))
+	greeter := manioc.MustResolve[IGreeter](manioc.WithScope(ctr))
+	assert.Equal("hello foo!", greeter.Greet())
+
+	// the registrations and the decorators are copied, but the cached instances are not
+	clone := ctr.Clone()
+	cloned := manioc.MustResolve[IGreeter](manioc.WithScope(clone))
+	assert.Equal("hello foo!", cloned.Greet())
+	assert.NotSame(greeter, cloned)
+	assert.Equal(manioc.Registrations(ctr), manioc.Registrations(clone))
+
+	// the changes of the registrations do not affect the other
+	assert.True(manioc.Unregister[*string](manioc.WithContainer(clone)))
+	assert.Nil(manioc.RegisterInstance(newName("bar"), manioc.WithContainer(clone)))
+	assert.Nil(manioc.RegisterInstance(newName("baz"), manioc.WithContainer(ctr), manioc.WithRegisterKey("baz")))
+	assert.Equal("hello foo!", manioc.MustResolve[IGreeter](manioc.WithScope(ctr)).Greet())
+	assert.Equal("hello bar!", manioc.MustResolve[IGreeter](manioc.WithScope(clone.Clone())).Greet())
+	assert.False(manioc.IsRegistered[*string](manioc.WithContainer(clone), manioc.WithRegisterKey("baz")))
+
+	// closing the clone does not affect the container
+	assert.Nil(clone.Close())
+	assert.Same(greeter, manioc.MustResolve[IGreeter](manioc.WithScope(ctr)))
+	_, err := manioc.Resolve[IGreeter](manioc.WithScope(clone))
+	assert.ErrorIs(err, manioc.ErrScopeClosed)
+}
+
+func Test_Clone_Child(t *testing.T) {
+	assert := assert.New(t)
+
+	parent := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(newName("foo"), manioc.WithContainer(parent)))
+	child := parent.NewChild()
+	assert.Nil(manioc.Register[IGreeter, Greeter](manioc.WithContainer(child)))
+
+	// the clone of the child container inherits the registrations of the same parent
+	clone := child.Clone()
+	assert.Nil(manioc.RegisterInstance(newName("bar"), manioc.WithContainer(parent), manioc.WithRegisterKey("bar")))
+	assert.Equal("hello foo", manioc.MustResolve[IGreeter](manioc.WithScope(clone)).Greet())
+	assert.True(manioc.IsRegistered[*string](manioc.WithContainer(clone), manioc.WithRegisterKey("bar")))
+	assert.False(manioc.IsRegistered[IGreeter](manioc.WithContainer(parent)))
+}
+
+func Test_Clone_ModulesAndHooks(t *testing.T) {
+	assert := assert.New(t)
+
+	module := manioc.NewModule("greeter", func(ctr manioc.Container) error {
+		return manioc.Register[IGreeter, Greeter](manioc.WithContainer(ctr))
+	})
+	started := 0
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Install(ctr, module))
+	assert.Nil(manioc.RegisterHook(manioc.Hook{
+		OnStart: func(ctx context.Context) error { started++; return nil },
+	}, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Start(context.Background(), ctr))
+
+	// the installed modules and the hooks are copied, but the clone has not been started
+	clone := ctr.Clone()
+	assert.Equal([]string{"greeter"}, manioc.InstalledModules(clone))
+	assert.ErrorIs(manioc.Install(clone, module), manioc.ErrModuleAlreadyInstalled)
+	assert.Len(manioc.Registrations(clone), 1)
+	assert.Nil(manioc.Start(context.Background(), clone))
+	assert.Equal(2, started)
+	assert.Nil(manioc.Stop(context.Background(), clone))
+	assert.Nil(manioc.Stop(context.Background(), ctr))
+}
+
+func Test_Clone_Closed(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(ctr.Close())
+	_, err := manioc.Resolve[IGreeter](manioc.WithScope(ctr.Clone()))
+	assert.ErrorIs(err, manioc.ErrScopeClosed)
+}
@@ -0,0 +1,282 @@
+package manioc_concurrency_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+// Run these tests with `go test -race` to detect data races.
+
+const numGoroutines = 64
+
+type IMyService interface {
+	doSomething()
+}
+
+// MyService implements IMyService
+type MyService struct {
+	Value int
+}
+
+func (s *MyService) doSomething() {}
+
+// parallel runs fn on numGoroutines goroutines at once.
+func parallel(fn func(i int)) {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+}
+
+func Test_Concurrency_RegisterAndResolve(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IMyService, MyService](manioc.WithContainer(ctr)))
+
+	parallel(func(i int) {
+		key := fmt.Sprintf("key%d", i)
+		assert.Nil(manioc.Register[IMyService, MyService](
+			manioc.WithContainer(ctr),
+			manioc.WithRegisterKey(key),
+		))
+		assert.True(manioc.IsRegistered[IMyService](manioc.WithContainer(ctr)))
+		_, err := manioc.Resolve[IMyService](manioc.WithScope(ctr))
+		assert.Nil(err)
+		_, err = manioc.Resolve[IMyService](manioc.WithScope(ctr), manioc.WithResolveKey(key))
+		assert.Nil(err)
+		assert.True(manioc.Unregister[IMyService](manioc.WithContainer(ctr), manioc.WithRegisterKey(key)))
+	})
+}
+
+func Test_Concurrency_GlobalCache(t *testing.T) {
+	assert := assert.New(t)
+
+	var count int32
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterSingletonConstructor[IMyService](
+		func() *MyService {
+			atomic.AddInt32(&count, 1)
+			return &MyService{}
+		},
+		manioc.WithContainer(ctr),
+	))
+
+	var instances [numGoroutines]IMyService
+	parallel(func(i int) {
+		// resolve from both the container and child scopes
+		scope, cleanup := ctr.OpenScope()
+		defer cleanup()
+		if i%2 == 0 {
+			scope = ctr
+		}
+		instances[i] = manioc.MustResolve[IMyService](manioc.WithScope(scope))
+	})
+
+	// the singleton is created exactly once
+	assert.Equal(int32(1), atomic.LoadInt32(&count))
+	for _, instance := range instances {
+		assert.Same(instances[0], instance)
+	}
+}
+
+func Test_Concurrency_ScopedCache(t *testing.T) {
+	assert := assert.New(t)
+
+	var count int32
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterScopedConstructor[IMyService](
+		func() *MyService {
+			atomic.AddInt32(&count, 1)
+			return &MyService{}
+		},
+		manioc.WithContainer(ctr),
+	))
+	scope, cleanup := ctr.OpenScope()
+	defer cleanup()
+
+	var instances [numGoroutines]IMyService
+	parallel(func(i int) {
+		instances[i] = manioc.MustResolve[IMyService](manioc.WithScope(scope))
+	})
+
+	// the scoped instance is created exactly once per scope
+	assert.Equal(int32(1), atomic.LoadInt32(&count))
+	for _, instance := range instances {
+		assert.Same(instances[0], instance)
+	}
+}
+
+func Test_Concurrency_FailedCreationIsNotCached(t *testing.T) {
+	assert := assert.New(t)
+
+	var count int32
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterSingletonConstructor[IMyService](
+		func() (*MyService, error) {
+			if atomic.AddInt32(&count, 1) == 1 {
+				return nil, fmt.Errorf("first attempt fails")
+			}
+			return &MyService{}, nil
+		},
+		manioc.WithContainer(ctr),
+	))
+
+	// the first resolution fails
+	_, err := manioc.Resolve[IMyService](manioc.WithScope(ctr))
+	assert.Error(err)
+
+	// the error is not cached, and the next attempts succeed with the same instance
+	var instances [numGoroutines]IMyService
+	parallel(func(i int) {
+		instances[i] = manioc.MustResolve[IMyService](manioc.WithScope(ctr))
+	})
+	assert.Equal(int32(2), atomic.LoadInt32(&count))
+	for _, instance := range instances {
+		assert.Same(instances[0], instance)
+	}
+}
+
+func Test_Concurrency_OpenAndCloseScopes(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterScoped[IMyService, MyService](manioc.WithContainer(ctr)))
+	parent, cleanupParent := ctr.OpenScope()
+
+	modes := []manioc.ScopeCacheMode{
+		manioc.DefaultCacheMode,
+		manioc.InheritCacheMode,
+		manioc.SyncCacheMode,
+	}
+	parallel(func(i int) {
+		if i == numGoroutines/2 {
+			// close the parent scope while children are being used
+			cleanupParent()
+			return
+		}
+		scope, cleanup := parent.OpenScope(manioc.WithCacheMode(modes[i%len(modes)]))
+		defer cleanup()
+		// resolution may fail if the parent has been closed, but it must not race
+		_, _ = manioc.Resolve[IMyService](manioc.WithScope(scope))
+		_, _ = manioc.Resolve[IMyService](manioc.WithScope(parent))
+	})
+}
+
+type (
+	CycleA struct{}
+	CycleB struct{}
+	// BarrierA and BarrierB are resolved while the singletons are being created
+	BarrierA struct{}
+	BarrierB struct{}
+)
+
+func Test_Concurrency_CircularDependency(t *testing.T) {
+	assert := assert.New(t)
+
+	// wait until both goroutines are creating their singletons
+	var arrived int32
+	ready := make(chan struct{})
+	barrier := func() {
+		if atomic.AddInt32(&arrived, 1) == 2 {
+			close(ready)
+		}
+		<-ready
+	}
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterConstructor[*BarrierA](
+		func() *BarrierA { barrier(); return &BarrierA{} },
+		manioc.WithContainer(ctr),
+	))
+	assert.Nil(manioc.RegisterConstructor[*BarrierB](
+		func() *BarrierB { barrier(); return &BarrierB{} },
+		manioc.WithContainer(ctr),
+	))
+	assert.Nil(manioc.RegisterSingletonConstructor[*CycleA](
+		func(_ *BarrierA, _ *CycleB) *CycleA { return &CycleA{} },
+		manioc.WithContainer(ctr),
+	))
+	assert.Nil(manioc.RegisterSingletonConstructor[*CycleB](
+		func(_ *BarrierB, _ *CycleA) *CycleB { return &CycleB{} },
+		manioc.WithContainer(ctr),
+	))
+
+	// resolve the opposite ends of the cycle at once
+	errs := make(chan error, 2)
+	go func() {
+		_, err := manioc.Resolve[*CycleA](manioc.WithScope(ctr))
+		errs <- err
+	}()
+	go func() {
+		_, err := manioc.Resolve[*CycleB](manioc.WithScope(ctr))
+		errs <- err
+	}()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			assert.ErrorIs(err, manioc.ErrCircularDependency)
+		case <-time.After(5 * time.Second):
+			assert.FailNow("deadlock")
+		}
+	}
+}
+
+type (
+	// Pool calls the factory of Slow on multiple goroutines in its constructor
+	Pool struct {
+		workers []*Slow
+	}
+	Slow struct{}
+)
+
+func Test_Concurrency_FactoryOnMultipleGoroutines(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterSingletonConstructor[*Slow](
+		func() *Slow {
+			// the other goroutines wait for the instance being created
+			time.Sleep(10 * time.Millisecond)
+			return &Slow{}
+		},
+		manioc.WithContainer(ctr),
+	))
+	assert.Nil(manioc.RegisterSingletonConstructor[*Pool](
+		func(newSlow func() (*Slow, error)) (*Pool, error) {
+			// the goroutines run within the same resolution, but they do not wait for each other
+			workers := make([]*Slow, numGoroutines)
+			errs := make([]error, numGoroutines)
+			parallel(func(i int) {
+				workers[i], errs[i] = newSlow()
+			})
+			for _, err := range errs {
+				if err != nil {
+					return nil, err
+				}
+			}
+			return &Pool{workers: workers}, nil
+		},
+		manioc.WithContainer(ctr),
+	))
+
+	pool, err := manioc.Resolve[*Pool](manioc.WithScope(ctr))
+	assert.Nil(err)
+	for _, worker := range pool.workers {
+		assert.Same(pool.workers[0], worker)
+	}
+}
@@ -0,0 +1,181 @@
+package manioc_conflict_policy_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IService interface {
+	Name() string
+}
+
+type Foo struct{}
+
+func (s *Foo) Name() string {
+	return "foo"
+}
+
+type Bar struct{}
+
+func (s *Bar) Name() string {
+	return "bar"
+}
+
+func names(services []IService) []string {
+	ret := make([]string, 0, len(services))
+	for _, s := range services {
+		ret = append(ret, s.Name())
+	}
+	return ret
+}
+
+func Test_Append(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IService, Foo](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IService, Bar](manioc.WithContainer(ctr), manioc.WithConflictPolicy(manioc.Append)))
+	// the default policy is Append
+	assert.Nil(manioc.Register[IService, Foo](manioc.WithContainer(ctr)))
+	services, err := manioc.ResolveMany[IService](manioc.WithScope(ctr))
+	assert.Nil(err)
+	assert.Equal([]string{"foo", "bar", "foo"}, names(services))
+	_, err = manioc.Resolve[IService](manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrAmbiguous)
+}
+
+func Test_Replace(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IService, Foo](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IService, Foo](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IService, Bar](manioc.WithContainer(ctr), manioc.WithConflictPolicy(manioc.Replace)))
+	services, err := manioc.ResolveMany[IService](manioc.WithScope(ctr))
+	assert.Nil(err)
+	assert.Equal([]string{"bar"}, names(services))
+	assert.Equal("bar", manioc.MustResolve[IService](manioc.WithScope(ctr)).Name())
+	assert.Len(manioc.Registrations(ctr), 1)
+
+	// the registrations with other keys are not affected
+	assert.Nil(manioc.Register[IService, Foo](manioc.WithContainer(ctr), manioc.WithRegisterKey("foo"),
+		manioc.WithConflictPolicy(manioc.Replace)))
+	assert.Equal("bar", manioc.MustResolve[IService](manioc.WithScope(ctr)).Name())
+	assert.Equal("foo", manioc.MustResolve[IService](manioc.WithScope(ctr), manioc.WithResolveKey("foo")).Name())
+}
+
+func Test_KeepExisting(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	// the registration is made if no registration exists
+	assert.Nil(manioc.Register[IService, Foo](manioc.WithContainer(ctr), manioc.WithConflictPolicy(manioc.KeepExisting)))
+	assert.Nil(manioc.Register[IService, Bar](manioc.WithContainer(ctr), manioc.WithConflictPolicy(manioc.KeepExisting)))
+	assert.Equal("foo", manioc.MustResolve[IService](manioc.WithScope(ctr)).Name())
+	assert.Len(manioc.Registrations(ctr), 1)
+}
+
+func Test_FailOnDuplicate(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IService, Foo](
+		manioc.WithContainer(ctr),
+		manioc.WithConflictPolicy(manioc.FailOnDuplicate),
+	))
+	err := manioc.Register[IService, Bar](manioc.WithContainer(ctr), manioc.WithConflictPolicy(manioc.FailOnDuplicate))
+	assert.ErrorIs(err, manioc.ErrDuplicateRegistration)
+	// the error names both registration sites
+	assert.Regexp(
+		`registered at .*conflict_policy_test\.go:\d+ has already been registered at .*conflict_policy_test\.go:\d+$`,
+		err.Error(),
+	)
+	assert.Equal("foo", manioc.MustResolve[IService](manioc.WithScope(ctr)).Name())
+	assert.Len(manioc.Registrations(ctr), 1)
+
+	// the registrations with other keys are not duplicates
+	assert.Nil(manioc.Register[IService, Bar](manioc.WithContainer(ctr), manioc.WithRegisterKey("bar"),
+		manioc.WithConflictPolicy(manioc.FailOnDuplicate)))
+	// after unregistering, the service can be registered again
+	assert.True(manioc.Unregister[IService](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IService, Bar](
+		manioc.WithContainer(ctr),
+		manioc.WithConflictPolicy(manioc.FailOnDuplicate),
+	))
+}
+
+func Test_DefaultConflictPolicy(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer(manioc.WithDefaultConflictPolicy(manioc.FailOnDuplicate))
+	assert.Nil(manioc.RegisterInstance[IService](&Foo{}, manioc.WithContainer(ctr)))
+	err := manioc.RegisterInstance[IService](&Bar{}, manioc.WithContainer(ctr))
+	assert.True(errors.Is(err, manioc.ErrDuplicateRegistration))
+	// the option of the registration takes precedence over the default of the container
+	assert.Nil(manioc.RegisterInstance[IService](
+		&Bar{},
+		manioc.WithContainer(ctr),
+		manioc.WithConflictPolicy(manioc.Replace),
+	))
+	assert.Equal("bar", manioc.MustResolve[IService](manioc.WithScope(ctr)).Name())
+
+	// the child container inherits the default, and the inherited registrations are not duplicates
+	child := ctr.NewChild()
+	assert.Nil(manioc.Register[IService, Foo](manioc.WithContainer(child)))
+	assert.Equal("foo", manioc.MustResolve[IService](manioc.WithScope(child)).Name())
+	err = manioc.Register[IService, Foo](manioc.WithContainer(child))
+	assert.ErrorIs(err, manioc.ErrDuplicateRegistration)
+
+	// the clone also inherits the default
+	err = manioc.Register[IService, Foo](manioc.WithContainer(ctr.Clone()))
+	assert.ErrorIs(err, manioc.ErrDuplicateRegistration)
+}
+
+func Test_ScopedInstance(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer(manioc.WithDefaultConflictPolicy(manioc.FailOnDuplicate))
+	assert.Nil(manioc.Register[IService, Foo](manioc.WithContainer(ctr)))
+	scope, closeScope := ctr.OpenScope()
+	defer closeScope()
+	// the registrations in the container are not conflicts of the scoped instances
+	assert.Nil(manioc.RegisterScopedInstance[IService](scope, &Bar{}))
+	err := manioc.RegisterScopedInstance[IService](scope, &Foo{})
+	assert.ErrorIs(err, manioc.ErrDuplicateRegistration)
+	assert.Equal("bar", manioc.MustResolve[IService](manioc.WithScope(scope)).Name())
+
+	// the instances registered for the parent scope are not conflicts either
+	child, closeChild := scope.OpenScope(manioc.WithCacheMode(manioc.InheritCacheMode))
+	defer closeChild()
+	assert.Nil(manioc.RegisterScopedInstance[IService](child, &Foo{}))
+	assert.Equal("foo", manioc.MustResolve[IService](manioc.WithScope(child)).Name())
+
+	// the option of the registration takes precedence over the default of the container
+	assert.Nil(manioc.RegisterScopedInstance[IService](scope, &Foo{}, manioc.WithConflictPolicy(manioc.Replace)))
+	assert.Equal("foo", manioc.MustResolve[IService](manioc.WithScope(scope)).Name())
+	assert.Nil(manioc.RegisterScopedInstance[IService](scope, &Bar{}, manioc.WithConflictPolicy(manioc.KeepExisting)))
+	assert.Equal("foo", manioc.MustResolve[IService](manioc.WithScope(scope)).Name())
+}
+
+func Test_InvalidConflictPolicy(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Panics(func() {
+		manioc.WithConflictPolicy(manioc.ConflictPolicy(-1))
+	})
+	assert.Panics(func() {
+		manioc.WithDefaultConflictPolicy(manioc.ConflictPolicy(4))
+	})
+	assert.Equal("FailOnDuplicate", manioc.FailOnDuplicate.String())
+	assert.Equal("ConflictPolicy(4)", manioc.ConflictPolicy(4).String())
+}
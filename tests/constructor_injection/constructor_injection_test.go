@@ -151,3 +151,95 @@ func Test_ConstructorInjection_Recursive(t *testing.T) {
 	assert.True(ok)
 	assert.Len(bar.foo, 1)
 }
+
+func Test_ConstructorInjection_WithArgumentKey(t *testing.T) {
+	t.Run("resolve arguments with service keys", func(t *testing.T) {
+		assert := assert.New(t)
+
+		// register
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService1](manioc.WithContainer(ctr)))
+		assert.Nil(manioc.Register[IFooService, FooService2](
+			manioc.WithContainer(ctr),
+			manioc.WithRegisterKey("another"),
+		))
+		assert.Nil(manioc.Register[IBarService, BarService](
+			manioc.WithContainer(ctr),
+			manioc.WithRegisterKey("bar"),
+		))
+		assert.Nil(manioc.RegisterConstructor[IBazService](
+			NewBazService,
+			manioc.WithContainer(ctr),
+			manioc.WithArgumentKey(0, "another"),
+			manioc.WithArgumentKey(1, "bar"),
+		))
+
+		// resolve
+		ret, err := manioc.Resolve[IBazService](manioc.WithScope(ctr))
+		assert.Nil(err)
+
+		// check injected instances
+		baz, ok := ret.(*BazService)
+		assert.True(ok)
+		_, ok = baz.foo.(*FooService2)
+		assert.True(ok)
+		assert.NotNil(baz.bar)
+	})
+
+	t.Run("resolve many with service keys", func(t *testing.T) {
+		assert := assert.New(t)
+
+		// register
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService1](manioc.WithContainer(ctr)))
+		assert.Nil(manioc.Register[IFooService, FooService1](manioc.WithContainer(ctr), manioc.WithRegisterKey("many")))
+		assert.Nil(manioc.Register[IFooService, FooService2](manioc.WithContainer(ctr), manioc.WithRegisterKey("many")))
+		assert.Nil(manioc.RegisterConstructor[IBarService](
+			NewBarServiceWithManyFoo,
+			manioc.WithContainer(ctr),
+			manioc.WithArgumentKey(0, "many"),
+		))
+
+		// resolve
+		bar, ok := manioc.MustResolve[IBarService](manioc.WithScope(ctr)).(*BarService)
+		assert.True(ok)
+		assert.Len(bar.foo, 2)
+	})
+
+	t.Run("arguments without keys are resolved without keys", func(t *testing.T) {
+		assert := assert.New(t)
+
+		// register
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService1](manioc.WithContainer(ctr)))
+		assert.Nil(manioc.RegisterConstructor[IBazService](
+			NewBazService,
+			manioc.WithContainer(ctr),
+			manioc.WithArgumentKey(1, "bar"),
+		))
+
+		// resolution will fail since IBarService with key=bar is not registered
+		_, err := manioc.Resolve[IBazService](manioc.WithScope(ctr))
+		assert.ErrorIs(err, manioc.ErrNotRegistered)
+		var resolveErr *manioc.ResolveError
+		assert.ErrorAs(err, &resolveErr)
+		assert.Equal("bar", resolveErr.ServiceKey)
+	})
+
+	t.Run("argument index out of range", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := manioc.NewContainer()
+		assert.Error(manioc.RegisterConstructor[IBazService](
+			NewBazService,
+			manioc.WithContainer(ctr),
+			manioc.WithArgumentKey(2, "foo"),
+		))
+		assert.Error(manioc.RegisterConstructor[IBazService](
+			NewBazService,
+			manioc.WithContainer(ctr),
+			manioc.WithArgumentKey(-1, "foo"),
+		))
+		assert.False(manioc.IsRegistered[IBazService](manioc.WithContainer(ctr)))
+	})
+}
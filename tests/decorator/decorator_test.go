@@ -0,0 +1,232 @@
+package manioc_decorator_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IGreeter interface {
+	Greet() string
+}
+
+// Greeter implements IGreeter
+type Greeter struct {
+	Name string
+}
+
+func (g *Greeter) Greet() string {
+	return "hello " + g.Name
+}
+
+// Logger is a dependency of the decorator
+type Logger struct {
+	Logs []string
+}
+
+// LoggingGreeter decorates IGreeter
+type LoggingGreeter struct {
+	inner  IGreeter
+	logger *Logger
+}
+
+func (g *LoggingGreeter) Greet() string {
+	ret := g.inner.Greet()
+	g.logger.Logs = append(g.logger.Logs, ret)
+	return ret
+}
+
+func WithLogging(inner IGreeter, logger *Logger) IGreeter {
+	return &LoggingGreeter{inner: inner, logger: logger}
+}
+
+// ExclaimingGreeter decorates IGreeter
+type ExclaimingGreeter struct {
+	inner IGreeter
+}
+
+func (g *ExclaimingGreeter) Greet() string {
+	return g.inner.Greet() + "!"
+}
+
+func WithExclamation(inner IGreeter) *ExclaimingGreeter {
+	return &ExclaimingGreeter{inner: inner}
+}
+
+func Test_Decorate(t *testing.T) {
+	assert := assert.New(t)
+
+	logger := &Logger{Logs: make([]string, 0)}
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(logger, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterConstructor[IGreeter](
+		func() *Greeter { return &Greeter{Name: "world"} },
+		manioc.WithContainer(ctr),
+	))
+
+	// decorators are applied in the order of registration
+	assert.Nil(manioc.Decorate[IGreeter](WithLogging, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Decorate[IGreeter](WithExclamation, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Validate(ctr))
+
+	ret := manioc.MustResolve[IGreeter](manioc.WithScope(ctr))
+	assert.IsType(&ExclaimingGreeter{}, ret)
+	assert.Equal("hello world!", ret.Greet())
+	assert.Equal([]string{"hello world"}, logger.Logs)
+}
+
+func Test_Decorate_RegisteredAfterwards(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Decorate[IGreeter](WithExclamation, manioc.WithContainer(ctr)))
+
+	// the decorator applies to registrations made after Decorate, including all of ResolveMany
+	assert.Nil(manioc.RegisterInstance[IGreeter](&Greeter{Name: "foo"}, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterInstance[IGreeter](&Greeter{Name: "bar"}, manioc.WithContainer(ctr)))
+	ret := manioc.MustResolveMany[IGreeter](manioc.WithScope(ctr))
+	assert.Len(ret, 2)
+	assert.Equal("hello foo!", ret[0].Greet())
+	assert.Equal("hello bar!", ret[1].Greet())
+}
+
+func Test_Decorate_WithKey(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance[IGreeter](&Greeter{Name: "foo"}, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterInstance[IGreeter](
+		&Greeter{Name: "bar"},
+		manioc.WithContainer(ctr),
+		manioc.WithRegisterKey("bar"),
+	))
+	assert.Nil(manioc.RegisterInstance(&Logger{}, manioc.WithContainer(ctr), manioc.WithRegisterKey("logger")))
+	assert.Nil(manioc.Decorate[IGreeter](
+		WithLogging,
+		manioc.WithContainer(ctr),
+		manioc.WithRegisterKey("bar"),
+		manioc.WithArgumentKey(1, "logger"),
+	))
+
+	// the decorator only applies to the registrations with the same key
+	assert.IsType(&Greeter{}, manioc.MustResolve[IGreeter](manioc.WithScope(ctr)))
+	assert.IsType(&LoggingGreeter{}, manioc.MustResolve[IGreeter](
+		manioc.WithScope(ctr),
+		manioc.WithResolveKey("bar"),
+	))
+}
+
+func Test_Decorate_CachePolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	count := 0
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterSingleton[IGreeter, Greeter](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Decorate[IGreeter](
+		func(inner IGreeter) IGreeter {
+			count++
+			return WithExclamation(inner)
+		},
+		manioc.WithContainer(ctr),
+	))
+
+	// the decorated instance is cached
+	ret1 := manioc.MustResolve[IGreeter](manioc.WithScope(ctr))
+	ret2 := manioc.MustResolve[IGreeter](manioc.WithScope(ctr))
+	assert.IsType(&ExclaimingGreeter{}, ret1)
+	assert.Same(ret1, ret2)
+	assert.Equal(1, count)
+}
+
+func Test_Decorate_Errors(t *testing.T) {
+	t.Run("decorator returns error", func(t *testing.T) {
+		assert := assert.New(t)
+
+		errDecorate := errors.New("failed to decorate")
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[IGreeter, Greeter](manioc.WithContainer(ctr)))
+		assert.Nil(manioc.Decorate[IGreeter](
+			func(inner IGreeter) (IGreeter, error) { return nil, errDecorate },
+			manioc.WithContainer(ctr),
+		))
+
+		_, err := manioc.Resolve[IGreeter](manioc.WithScope(ctr))
+		assert.ErrorIs(err, manioc.ErrDecoratorFailed)
+		assert.ErrorIs(err, errDecorate)
+	})
+
+	t.Run("missing dependencies of decorator", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[IGreeter, Greeter](manioc.WithContainer(ctr)))
+		assert.Nil(manioc.Decorate[IGreeter](WithLogging, manioc.WithContainer(ctr)))
+
+		_, err := manioc.Resolve[IGreeter](manioc.WithScope(ctr))
+		assert.ErrorIs(err, manioc.ErrNotRegistered)
+		assert.ErrorIs(manioc.Validate(ctr), manioc.ErrNotRegistered)
+	})
+
+	t.Run("invalid decorators", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := manioc.NewContainer()
+		assert.Panics(func() {
+			_ = manioc.Decorate[IGreeter](fmt.Sprintf, manioc.WithContainer(ctr))
+		})
+		assert.Panics(func() {
+			_ = manioc.Decorate[IGreeter](func(inner IGreeter) string { return "" }, manioc.WithContainer(ctr))
+		})
+		assert.Panics(func() {
+			_ = manioc.Decorate[IGreeter](func(inner *Greeter) IGreeter { return inner }, manioc.WithContainer(ctr))
+		})
+		assert.Error(manioc.Decorate[IGreeter]((func(IGreeter) IGreeter)(nil), manioc.WithContainer(ctr)))
+		assert.Error(manioc.Decorate[IGreeter](
+			WithExclamation,
+			manioc.WithContainer(ctr),
+			manioc.WithArgumentKey(0, "foo"),
+		))
+	})
+}
+
+func Test_OnResolved(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterConstructor[IGreeter](
+		func() *Greeter { return &Greeter{Name: "world"} },
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.GlobalCache),
+	))
+	assert.Nil(manioc.Decorate[IGreeter](WithExclamation, manioc.WithContainer(ctr)))
+	resolved := make([]IGreeter, 0)
+	assert.Nil(manioc.OnResolved(func(g IGreeter) {
+		resolved = append(resolved, g)
+	}, manioc.WithContainer(ctr)))
+
+	// the function is called with the decorated instance, including the ones served from the cache
+	first := manioc.MustResolve[IGreeter](manioc.WithScope(ctr))
+	second := manioc.MustResolve[[]IGreeter](manioc.WithScope(ctr))
+	assert.Equal([]IGreeter{first, first}, resolved)
+	assert.Equal([]IGreeter{first}, second)
+
+	// the function applies to the clones and the child containers
+	manioc.MustResolve[IGreeter](manioc.WithScope(ctr.Clone()))
+	manioc.MustResolve[IGreeter](manioc.WithScope(ctr.NewChild()))
+	assert.Len(resolved, 4)
+
+	// the function is not called if the resolution fails
+	assert.Nil(manioc.OnResolved(func(g *Greeter) {
+		resolved = append(resolved, g)
+	}, manioc.WithContainer(ctr)))
+	_, err := manioc.Resolve[*Greeter](manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrNotRegistered)
+	assert.Len(resolved, 4)
+
+	// the closed container
+	assert.Nil(ctr.Close())
+	assert.ErrorIs(manioc.OnResolved(func(g IGreeter) {}, manioc.WithContainer(ctr)), manioc.ErrScopeClosed)
+}
@@ -0,0 +1,309 @@
+package manioc_deferred_resolution_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IFooService interface {
+	doFoo()
+}
+
+// FooService implements IFooService
+type FooService struct {
+	Value int
+}
+
+func (s *FooService) doFoo() {}
+
+// LazyService requires IFooService lazily
+type LazyService struct {
+	fooByField manioc.Lazy[IFooService] `manioc:"inject"`
+	fooByArg   manioc.Lazy[IFooService]
+}
+
+func NewLazyService(foo manioc.Lazy[IFooService]) *LazyService {
+	return &LazyService{fooByArg: foo}
+}
+
+// FactoryService requires a factory of IFooService
+type FactoryService struct {
+	newFooByField func() (IFooService, error) `manioc:"inject,key=foo"`
+	newFooByArg   func() (IFooService, error)
+}
+
+func NewFactoryService(newFoo func() (IFooService, error)) *FactoryService {
+	return &FactoryService{newFooByArg: newFoo}
+}
+
+func Test_Lazy(t *testing.T) {
+	assert := assert.New(t)
+
+	count := 0
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterScopedConstructor[IFooService](
+		func() *FooService {
+			count++
+			return &FooService{}
+		},
+		manioc.WithContainer(ctr),
+	))
+	assert.Nil(manioc.RegisterConstructor[*LazyService](NewLazyService, manioc.WithContainer(ctr)))
+
+	scope, cleanup := ctr.OpenScope()
+	defer cleanup()
+	ret := manioc.MustResolve[*LazyService](manioc.WithScope(scope))
+
+	// not resolved yet
+	assert.Equal(0, count)
+
+	// resolved on the first call of Get
+	foo1, err := ret.fooByArg.Get()
+	assert.Nil(err)
+	assert.Equal(1, count)
+	foo2 := ret.fooByField.MustGet()
+	assert.Same(foo1, foo2)
+
+	// the Lazy is bound to the scope where it is injected
+	assert.Same(foo1, manioc.MustResolve[IFooService](manioc.WithScope(scope)))
+	assert.NotSame(foo1, manioc.MustResolve[IFooService](manioc.WithScope(ctr)))
+}
+
+func Test_Lazy_Errors(t *testing.T) {
+	t.Run("not registered", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.RegisterConstructor[*LazyService](NewLazyService, manioc.WithContainer(ctr)))
+
+		// the resolution of the Lazy itself succeeds
+		ret := manioc.MustResolve[*LazyService](manioc.WithScope(ctr))
+
+		// but Get fails
+		_, err := ret.fooByArg.Get()
+		assert.ErrorIs(err, manioc.ErrNotRegistered)
+		assert.Panics(func() { ret.fooByField.MustGet() })
+
+		// after the registration, Get succeeds
+		assert.Nil(manioc.Register[IFooService, FooService](manioc.WithContainer(ctr)))
+		_, err = ret.fooByArg.Get()
+		assert.Nil(err)
+	})
+
+	t.Run("scope closed", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService](manioc.WithContainer(ctr)))
+		assert.Nil(manioc.RegisterConstructor[*LazyService](NewLazyService, manioc.WithContainer(ctr)))
+
+		scope, cleanup := ctr.OpenScope()
+		ret := manioc.MustResolve[*LazyService](manioc.WithScope(scope))
+		cleanup()
+		_, err := ret.fooByArg.Get()
+		assert.ErrorIs(err, manioc.ErrScopeClosed)
+	})
+
+	t.Run("not injected", func(t *testing.T) {
+		var lazy manioc.Lazy[IFooService]
+		_, err := lazy.Get()
+		assert.Error(t, err)
+	})
+}
+
+func Test_Lazy_CacheOwner(t *testing.T) {
+	t.Run("singleton", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService](manioc.WithContainer(ctr)))
+		assert.Nil(manioc.RegisterSingletonConstructor[*LazyService](NewLazyService, manioc.WithContainer(ctr)))
+		assert.Nil(manioc.RegisterSingletonConstructor[*FactoryService](NewFactoryService, manioc.WithContainer(ctr)))
+		assert.Nil(manioc.Register[IFooService, FooService](manioc.WithContainer(ctr), manioc.WithRegisterKey("foo")))
+
+		// the singletons are first resolved within the scope
+		scope, cleanup := ctr.OpenScope()
+		lazy := manioc.MustResolve[*LazyService](manioc.WithScope(scope))
+		factory := manioc.MustResolve[*FactoryService](manioc.WithScope(scope))
+		cleanup()
+
+		// but they are bound to the container, which outlives the scope
+		_, err := lazy.fooByArg.Get()
+		assert.Nil(err)
+		_, err = lazy.fooByField.Get()
+		assert.Nil(err)
+		_, err = factory.newFooByArg()
+		assert.Nil(err)
+		_, err = factory.newFooByField()
+		assert.Nil(err)
+	})
+
+	t.Run("SyncCacheMode", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService](manioc.WithContainer(ctr)))
+		assert.Nil(manioc.RegisterScopedConstructor[*LazyService](NewLazyService, manioc.WithContainer(ctr)))
+
+		// the instance is cached in the parent scope, so it is bound to the parent scope
+		parent, closeParent := ctr.OpenScope()
+		child, closeChild := parent.OpenScope(manioc.WithCacheMode(manioc.SyncCacheMode))
+		ret := manioc.MustResolve[*LazyService](manioc.WithScope(child))
+		closeChild()
+		assert.Same(ret, manioc.MustResolve[*LazyService](manioc.WithScope(parent)))
+		_, err := ret.fooByArg.Get()
+		assert.Nil(err)
+
+		closeParent()
+		_, err = ret.fooByField.Get()
+		assert.ErrorIs(err, manioc.ErrScopeClosed)
+	})
+}
+
+// A and B depend on each other, but B requires A lazily
+type A struct {
+	b *B `manioc:"inject"`
+}
+
+type B struct {
+	a manioc.Lazy[*A] `manioc:"inject"`
+}
+
+func Test_Lazy_CircularDependency(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterSingleton[*A, *A](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterSingleton[*B, *B](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Validate(ctr))
+
+	a := manioc.MustResolve[*A](manioc.WithScope(ctr))
+	assert.Same(a, a.b.a.MustGet())
+}
+
+// C and D call the factories of each other in their constructors
+type C struct {
+	d *D
+}
+
+type D struct {
+	c *C
+}
+
+func NewC(newD func() (*D, error)) (*C, error) {
+	d, err := newD()
+	return &C{d: d}, err
+}
+
+func NewD(newC func() (*C, error)) (*D, error) {
+	c, err := newC()
+	return &D{c: c}, err
+}
+
+// E calls the Lazy in its constructor, without circular dependencies
+type E struct {
+	foo IFooService
+}
+
+func NewE(foo manioc.Lazy[IFooService]) (*E, error) {
+	ret, err := foo.Get()
+	return &E{foo: ret}, err
+}
+
+func Test_Deferred_CircularDependency(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterSingletonConstructor[*C](NewC, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterSingletonConstructor[*D](NewD, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterSingleton[IFooService, FooService](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterSingletonConstructor[*E](NewE, manioc.WithContainer(ctr)))
+
+	// the deferred resolutions within the constructors are chained to the resolution in progress,
+	// so the circular dependency is reported instead of waiting for the instance being created
+	done := make(chan error)
+	go func() {
+		_, err := manioc.Resolve[*C](manioc.WithScope(ctr))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.ErrorIs(err, manioc.ErrCircularDependency)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the resolution is deadlocked")
+	}
+
+	// the deferred resolutions without circular dependencies succeed
+	e, err := manioc.Resolve[*E](manioc.WithScope(ctr))
+	assert.Nil(err)
+	assert.Same(manioc.MustResolve[IFooService](manioc.WithScope(ctr)), e.foo)
+}
+
+func Test_Factory(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterTransient[IFooService, FooService](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterTransient[IFooService, FooService](
+		manioc.WithContainer(ctr),
+		manioc.WithRegisterKey("foo"),
+	))
+	assert.Nil(manioc.RegisterConstructor[*FactoryService](NewFactoryService, manioc.WithContainer(ctr)))
+	ret := manioc.MustResolve[*FactoryService](manioc.WithScope(ctr))
+
+	// each call creates a new transient instance
+	foo1, err := ret.newFooByArg()
+	assert.Nil(err)
+	foo2, err := ret.newFooByArg()
+	assert.Nil(err)
+	assert.NotSame(foo1, foo2)
+
+	// the key is applied to the resolution of the factory
+	foo3, err := ret.newFooByField()
+	assert.Nil(err)
+	assert.NotNil(foo3)
+
+	// the factory is resolvable directly
+	newFoo := manioc.MustResolve[func() (IFooService, error)](manioc.WithScope(ctr))
+	foo4, err := newFoo()
+	assert.Nil(err)
+	assert.NotNil(foo4)
+}
+
+func Test_Factory_Errors(t *testing.T) {
+	assert := assert.New(t)
+
+	errFoo := errors.New("failed to create foo")
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterConstructor[IFooService](
+		func() (*FooService, error) { return nil, errFoo },
+		manioc.WithContainer(ctr),
+	))
+	newFoo := manioc.MustResolve[func() (IFooService, error)](manioc.WithScope(ctr))
+	ret, err := newFoo()
+	assert.Nil(ret)
+	assert.ErrorIs(err, manioc.ErrConstructorFailed)
+	assert.ErrorIs(err, errFoo)
+
+	// the factory of unregistered service fails on call
+	newFactory := manioc.MustResolve[func() (*FactoryService, error)](manioc.WithScope(ctr))
+	_, err = newFactory()
+	assert.ErrorIs(err, manioc.ErrNotRegistered)
+
+	// the validation detects the missing dependency of the factory
+	assert.Nil(manioc.RegisterConstructor[*FactoryService](NewFactoryService, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterConstructor[*LazyService](
+		NewLazyService,
+		manioc.WithContainer(ctr),
+		manioc.WithRegisterKey("lazy"),
+	))
+	err = manioc.Validate(ctr)
+	assert.ErrorIs(err, manioc.ErrNotRegistered)
+	var resolveErr *manioc.ResolveError
+	assert.ErrorAs(err, &resolveErr)
+	assert.Equal("foo", resolveErr.ServiceKey)
+}
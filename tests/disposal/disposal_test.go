@@ -0,0 +1,217 @@
+package manioc_disposal_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder records the order of disposal
+type recorder struct {
+	disposed []string
+}
+
+// Closer implements io.Closer
+type Closer struct {
+	name     string
+	recorder *recorder
+	err      error
+}
+
+func (c *Closer) Close() error {
+	c.recorder.disposed = append(c.recorder.disposed, c.name)
+	return c.err
+}
+
+// Disposer implements manioc.Disposable
+type Disposer struct {
+	name     string
+	recorder *recorder
+	closer   *Closer
+}
+
+func (d *Disposer) Dispose() error {
+	d.recorder.disposed = append(d.recorder.disposed, d.name)
+	return nil
+}
+
+func setup(t *testing.T, policy manioc.CachePolicy) (manioc.Container, *recorder) {
+	t.Helper()
+	assert := assert.New(t)
+	rec := &recorder{disposed: make([]string, 0)}
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterConstructor[*Closer](
+		func() *Closer { return &Closer{name: "closer", recorder: rec, err: nil} },
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(policy),
+	))
+	// Disposer depends on Closer
+	assert.Nil(manioc.RegisterConstructor[*Disposer](
+		func(closer *Closer) *Disposer { return &Disposer{name: "disposer", recorder: rec, closer: closer} },
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(policy),
+	))
+	return ctr, rec
+}
+
+func Test_Dispose_Scope(t *testing.T) {
+	assert := assert.New(t)
+	ctr, rec := setup(t, manioc.ScopedCache)
+
+	scope, _ := ctr.OpenScope()
+	manioc.MustResolve[*Disposer](manioc.WithScope(scope))
+	assert.Empty(rec.disposed)
+
+	// disposed in reverse creation order
+	assert.Nil(scope.Close())
+	assert.Equal([]string{"disposer", "closer"}, rec.disposed)
+
+	// closing again does nothing
+	assert.Nil(scope.Close())
+	assert.Len(rec.disposed, 2)
+}
+
+func Test_Dispose_CleanupFunction(t *testing.T) {
+	assert := assert.New(t)
+	ctr, rec := setup(t, manioc.ScopedCache)
+
+	scope, cleanup := ctr.OpenScope()
+	manioc.MustResolve[*Disposer](manioc.WithScope(scope))
+	cleanup()
+	assert.Equal([]string{"disposer", "closer"}, rec.disposed)
+}
+
+func Test_Dispose_ChildScopes(t *testing.T) {
+	assert := assert.New(t)
+	ctr, rec := setup(t, manioc.ScopedCache)
+
+	parent, _ := ctr.OpenScope()
+	manioc.MustResolve[*Closer](manioc.WithScope(parent))
+
+	// the inherited instance is owned by the parent
+	inherit, _ := parent.OpenScope(manioc.WithCacheMode(manioc.InheritCacheMode))
+	manioc.MustResolve[*Disposer](manioc.WithScope(inherit))
+	assert.Nil(inherit.Close())
+	assert.Equal([]string{"disposer"}, rec.disposed)
+
+	// the instances created in synced scope are owned by the parent
+	sync, _ := parent.OpenScope(manioc.WithCacheMode(manioc.SyncCacheMode))
+	manioc.MustResolve[*Disposer](manioc.WithScope(sync))
+	assert.Nil(sync.Close())
+	assert.Equal([]string{"disposer"}, rec.disposed)
+
+	// closing the parent disposes all of them
+	assert.Nil(parent.Close())
+	assert.Equal([]string{"disposer", "disposer", "closer"}, rec.disposed)
+}
+
+func Test_Dispose_NeverCache(t *testing.T) {
+	assert := assert.New(t)
+	ctr, rec := setup(t, manioc.NeverCache)
+
+	// transient instances are not owned by any scope
+	scope, _ := ctr.OpenScope()
+	manioc.MustResolve[*Disposer](manioc.WithScope(scope))
+	assert.Nil(scope.Close())
+	assert.Nil(ctr.Close())
+	assert.Empty(rec.disposed)
+}
+
+func Test_Dispose_Container(t *testing.T) {
+	assert := assert.New(t)
+	ctr, rec := setup(t, manioc.GlobalCache)
+
+	// singletons are not disposed when a scope is closed
+	scope, _ := ctr.OpenScope()
+	manioc.MustResolve[*Disposer](manioc.WithScope(scope))
+	assert.Nil(scope.Close())
+	assert.Empty(rec.disposed)
+
+	// singletons are disposed when the container is closed
+	assert.Nil(ctr.Close())
+	assert.Equal([]string{"disposer", "closer"}, rec.disposed)
+
+	// after the container is closed, the resolution fails
+	_, err := manioc.Resolve[*Disposer](manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrScopeClosed)
+}
+
+func Test_Dispose_ScopeOutlivingContainer(t *testing.T) {
+	assert := assert.New(t)
+	ctr, rec := setup(t, manioc.GlobalCache)
+
+	// the scopes opened with DefaultCacheMode are not closed with the container
+	scope, _ := ctr.OpenScope()
+	assert.Nil(ctr.Close())
+
+	// but no singleton is created in the disposed cache of the container
+	_, err := manioc.Resolve[*Disposer](manioc.WithScope(scope))
+	assert.ErrorIs(err, manioc.ErrScopeClosed)
+	_, err = manioc.Resolve[*Closer](manioc.WithScope(scope))
+	assert.ErrorIs(err, manioc.ErrScopeClosed)
+	assert.Nil(scope.Close())
+	assert.Empty(rec.disposed)
+}
+
+func Test_Dispose_ClosedContainer(t *testing.T) {
+	assert := assert.New(t)
+	ctr, _ := setup(t, manioc.GlobalCache)
+	assert.Nil(ctr.Close())
+
+	// the operations on the closed container fail without panicking
+	assert.ErrorIs(manioc.Register[Closer, Closer](manioc.WithContainer(ctr)), manioc.ErrScopeClosed)
+	assert.ErrorIs(manioc.RegisterInstance(&Closer{}, manioc.WithContainer(ctr)), manioc.ErrScopeClosed)
+	assert.ErrorIs(manioc.Decorate[*Closer](func(c *Closer) *Closer { return c }, manioc.WithContainer(ctr)),
+		manioc.ErrScopeClosed)
+	assert.False(manioc.IsRegistered[*Closer](manioc.WithContainer(ctr)))
+	assert.False(manioc.Unregister[*Closer](manioc.WithContainer(ctr)))
+	assert.Empty(manioc.Registrations(ctr))
+	assert.ErrorIs(manioc.Validate(ctr), manioc.ErrScopeClosed)
+	_, err := manioc.Graph(ctr)
+	assert.ErrorIs(err, manioc.ErrScopeClosed)
+	assert.ErrorIs(manioc.Start(context.Background(), ctr), manioc.ErrScopeClosed)
+}
+
+func Test_Dispose_RegisteredInstance(t *testing.T) {
+	assert := assert.New(t)
+	rec := &recorder{disposed: make([]string, 0)}
+
+	// the instances given by the user are not owned by the container
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(&Closer{name: "instance", recorder: rec, err: nil}, manioc.WithContainer(ctr)))
+	manioc.MustResolve[*Closer](manioc.WithScope(ctr))
+	assert.Nil(ctr.Close())
+	assert.Empty(rec.disposed)
+}
+
+func Test_Dispose_Errors(t *testing.T) {
+	assert := assert.New(t)
+	rec := &recorder{disposed: make([]string, 0)}
+	err1 := errors.New("error1")
+	err2 := errors.New("error2")
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterSingletonConstructor[*Closer](
+		func() *Closer { return &Closer{name: "closer1", recorder: rec, err: err1} },
+		manioc.WithContainer(ctr),
+	))
+	assert.Nil(manioc.RegisterScopedConstructor[*Closer](
+		func() *Closer { return &Closer{name: "closer2", recorder: rec, err: err2} },
+		manioc.WithContainer(ctr),
+		manioc.WithRegisterKey("scoped"),
+	))
+	manioc.MustResolve[*Closer](manioc.WithScope(ctr))
+	manioc.MustResolve[*Closer](manioc.WithScope(ctr), manioc.WithResolveKey("scoped"))
+
+	// all instances are disposed even if some of them fail, and the errors are aggregated
+	err := ctr.Close()
+	assert.ErrorIs(err, err1)
+	assert.ErrorIs(err, err2)
+	var aggregated *manioc.AggregateError
+	assert.ErrorAs(err, &aggregated)
+	assert.Len(aggregated.Errors, 2)
+	assert.ElementsMatch([]string{"closer1", "closer2"}, rec.disposed)
+}
@@ -0,0 +1,251 @@
+package manioc_generated_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IMyService interface {
+	Name() string
+}
+
+type Config struct {
+	Name string
+}
+
+func (c *Config) Clone() *Config {
+	return &Config{Name: c.Name}
+}
+
+// newNamedConfig returns the function literals, which share the same code for the same type argument
+func newNamedConfig[T any](name T) func() *Config {
+	return func() *Config { return &Config{Name: fmt.Sprint(name)} }
+}
+
+type Logger struct{}
+
+// MyService implements IMyService
+type MyService struct {
+	config *Config `manioc:"inject"`
+	logger *Logger `manioc:"inject,optional"`
+}
+
+func (s *MyService) Name() string {
+	return s.config.Name
+}
+
+func NewMyService(config *Config) (*MyService, error) {
+	if config.Name == "" {
+		return nil, errors.New("empty name")
+	}
+	return &MyService{config: config, logger: nil}, nil
+}
+
+// the fields are not injected if the constructor returns nil
+func NewNilableService(config *Config) *MyService {
+	if config.Name == "" {
+		return nil
+	}
+	return &MyService{config: config, logger: nil}
+}
+
+// AnotherService implements IMyService, without the generated activation
+type AnotherService struct {
+	config *Config `manioc:"inject,key=another"`
+}
+
+func (s *AnotherService) Name() string {
+	return s.config.Name
+}
+
+//nolint:gochecknoglobals
+var activations = map[string]int{}
+
+// the same as the code generated by `manioc gen`, with counting the activations
+func init() {
+	manioc.UseGeneratedType[*MyService](func(a *manioc.Activation) (any, error) {
+		activations["MyService"]++
+		ret := new(MyService)
+		field0, err := manioc.Field[*Config](a, nil)
+		if err != nil {
+			return nil, err
+		}
+		ret.config = field0
+		if field1, ok, err := manioc.OptionalField[*Logger](a, nil); err != nil {
+			return nil, err
+		} else if ok {
+			ret.logger = field1
+		}
+		return ret, nil
+	})
+	manioc.UseGeneratedConstructor(NewMyService, func(a *manioc.Activation) (any, error) {
+		activations["NewMyService"]++
+		arg0, err := manioc.Argument[*Config](a, 0)
+		if err != nil {
+			return nil, err
+		}
+		ret, err := NewMyService(arg0)
+		if err != nil {
+			return nil, a.Fail(err)
+		}
+		if ret != nil {
+			field0, err := manioc.Field[*Config](a, nil)
+			if err != nil {
+				return nil, err
+			}
+			ret.config = field0
+			if field1, ok, err := manioc.OptionalField[*Logger](a, nil); err != nil {
+				return nil, err
+			} else if ok {
+				ret.logger = field1
+			}
+		}
+		return ret, nil
+	})
+	manioc.UseGeneratedConstructor(NewNilableService, func(a *manioc.Activation) (any, error) {
+		activations["NewNilableService"]++
+		arg0, err := manioc.Argument[*Config](a, 0)
+		if err != nil {
+			return nil, err
+		}
+		ret := NewNilableService(arg0)
+		if ret != nil {
+			field0, err := manioc.Field[*Config](a, nil)
+			if err != nil {
+				return nil, err
+			}
+			ret.config = field0
+			if field1, ok, err := manioc.OptionalField[*Logger](a, nil); err != nil {
+				return nil, err
+			} else if ok {
+				ret.logger = field1
+			}
+		}
+		return ret, nil
+	})
+}
+
+func Test_Generated_Type(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(&Config{Name: "foo"}, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IMyService, MyService](manioc.WithContainer(ctr)))
+	activations["MyService"] = 0
+
+	ret := manioc.MustResolve[IMyService](manioc.WithScope(ctr))
+	assert.Equal("foo", ret.Name())
+	assert.Nil(ret.(*MyService).logger)
+	assert.Equal(1, activations["MyService"])
+
+	// optional fields are injected if registered
+	logger := &Logger{}
+	assert.Nil(manioc.RegisterInstance(logger, manioc.WithContainer(ctr)))
+	assert.Same(logger, manioc.MustResolve[IMyService](manioc.WithScope(ctr)).(*MyService).logger)
+	assert.Equal(2, activations["MyService"])
+
+	// the dependencies are the same as the reflection-based activation
+	assert.Nil(manioc.Validate(ctr))
+	assert.True(manioc.Unregister[*Config](manioc.WithContainer(ctr)))
+	assert.ErrorIs(manioc.Validate(ctr), manioc.ErrNotRegistered)
+	_, err := manioc.Resolve[IMyService](manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrNotRegistered)
+}
+
+func Test_Generated_Constructor(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(&Config{Name: "foo"}, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterInstance(&Config{Name: ""}, manioc.WithContainer(ctr), manioc.WithRegisterKey("empty")))
+	assert.Nil(manioc.RegisterConstructor[IMyService](NewMyService, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterConstructor[IMyService](
+		NewMyService,
+		manioc.WithContainer(ctr),
+		manioc.WithRegisterKey("empty"),
+		manioc.WithArgumentKey(0, "empty"),
+	))
+	activations["NewMyService"] = 0
+
+	assert.Equal("foo", manioc.MustResolve[IMyService](manioc.WithScope(ctr)).Name())
+	assert.Equal(1, activations["NewMyService"])
+
+	// the argument keys are honored, and the errors of the constructor are wrapped
+	_, err := manioc.Resolve[IMyService](manioc.WithScope(ctr), manioc.WithResolveKey("empty"))
+	assert.ErrorIs(err, manioc.ErrConstructorFailed)
+	assert.Equal(2, activations["NewMyService"])
+}
+
+func Test_Generated_NilConstructor(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(&Config{Name: "foo"}, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterInstance(&Config{Name: ""}, manioc.WithContainer(ctr), manioc.WithRegisterKey("empty")))
+	assert.Nil(manioc.RegisterConstructor[*MyService](NewNilableService, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterConstructor[*MyService](
+		NewNilableService,
+		manioc.WithContainer(ctr),
+		manioc.WithRegisterKey("empty"),
+		manioc.WithArgumentKey(0, "empty"),
+	))
+	activations["NewNilableService"] = 0
+
+	assert.Equal("foo", manioc.MustResolve[*MyService](manioc.WithScope(ctr)).Name())
+	// the fields are not injected into nil, the same as the reflection-based activation
+	ret, err := manioc.Resolve[*MyService](manioc.WithScope(ctr), manioc.WithResolveKey("empty"))
+	assert.Nil(err)
+	assert.Nil(ret)
+	assert.Equal(2, activations["NewNilableService"])
+}
+
+func Test_Generated_Fallback(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(&Config{Name: "foo"}, manioc.WithContainer(ctr), manioc.WithRegisterKey("another")))
+	assert.Nil(manioc.Register[IMyService, AnotherService](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterConstructor[*Config](
+		func() *Config { return &Config{Name: "bar"} },
+		manioc.WithContainer(ctr),
+	))
+
+	// the registrations without the generated activation are activated with reflection
+	assert.Equal("foo", manioc.MustResolve[IMyService](manioc.WithScope(ctr)).Name())
+	assert.Equal("bar", manioc.MustResolve[*Config](manioc.WithScope(ctr)).Name)
+}
+
+func Test_Generated_Errors(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Panics(func() {
+		manioc.UseGeneratedType[*Config](nil)
+	})
+	assert.Panics(func() {
+		manioc.UseGeneratedConstructor("NewConfig", func(a *manioc.Activation) (any, error) { return nil, nil })
+	})
+
+	// the function literals and the method values are rejected, since their values share the same code
+	newConfig := func(name string) func() *Config {
+		return func() *Config { return &Config{Name: name} }
+	}
+	assert.Panics(func() {
+		manioc.UseGeneratedConstructor(newConfig("foo"), func(a *manioc.Activation) (any, error) { return nil, nil })
+	})
+	// including the ones in the generic functions
+	assert.Panics(func() {
+		manioc.UseGeneratedConstructor(newNamedConfig[int](1), func(a *manioc.Activation) (any, error) { return nil, nil })
+	})
+	config := &Config{Name: "foo"}
+	assert.Panics(func() {
+		manioc.UseGeneratedConstructor(config.Clone, func(a *manioc.Activation) (any, error) { return nil, nil })
+	})
+	var nilConstructor func() *Config
+	assert.Panics(func() {
+		manioc.UseGeneratedConstructor(nilConstructor, func(a *manioc.Activation) (any, error) { return nil, nil })
+	})
+}
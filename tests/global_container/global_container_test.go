@@ -0,0 +1,160 @@
+package manioc_global_container_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IService interface {
+	Name() string
+}
+
+type Service struct {
+	name string
+}
+
+func (s *Service) Name() string {
+	return s.name
+}
+
+// DisposableService records whether it has been disposed
+type DisposableService struct {
+	disposed bool
+}
+
+func (s *DisposableService) Close() error {
+	s.disposed = true
+	return nil
+}
+
+func Test_SetGlobalContainer(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	original := manioc.SetGlobalContainer(ctr)
+	defer manioc.SetGlobalContainer(original)
+	assert.Same(ctr, manioc.GlobalContainer())
+
+	// the global container is used when neither WithContainer nor WithScope is specified
+	assert.Nil(manioc.RegisterInstance[IService](&Service{name: "foo"}))
+	assert.Equal("foo", manioc.MustResolve[IService]().Name())
+	assert.Equal("foo", manioc.MustResolve[IService](manioc.WithScope(ctr)).Name())
+	assert.False(manioc.IsRegistered[IService](manioc.WithContainer(original)))
+
+	// the replaced container is returned
+	assert.Same(ctr, manioc.SetGlobalContainer(original))
+	assert.Same(original, manioc.GlobalContainer())
+	assert.False(manioc.IsRegistered[IService]())
+
+	assert.Panics(func() {
+		manioc.SetGlobalContainer(nil)
+	})
+	assert.Panics(func() {
+		manioc.RestoreGlobal(nil)
+	})
+}
+
+func Test_SnapshotAndRestoreGlobal(t *testing.T) {
+	assert := assert.New(t)
+
+	original := manioc.SetGlobalContainer(manioc.NewContainer())
+	defer manioc.SetGlobalContainer(original)
+
+	assert.Nil(manioc.RegisterSingleton[IService, Service]())
+	singleton := manioc.MustResolve[IService]()
+	snapshot := manioc.SnapshotGlobal()
+
+	// the snapshot does not change the global container
+	assert.Nil(manioc.RegisterInstance[*string](new(string)))
+	assert.True(manioc.Unregister[IService]())
+	assert.True(manioc.IsRegistered[*string]())
+
+	// the registrations and the cached singletons are restored
+	manioc.RestoreGlobal(snapshot)
+	assert.True(manioc.IsRegistered[IService]())
+	assert.False(manioc.IsRegistered[*string]())
+	assert.Same(singleton, manioc.MustResolve[IService]())
+
+	// the changes after restoring do not affect the snapshot
+	assert.True(manioc.Unregister[IService]())
+	manioc.RestoreGlobal(snapshot)
+	assert.Same(singleton, manioc.MustResolve[IService]())
+}
+
+func Test_SnapshotGlobal_ScopedCache(t *testing.T) {
+	assert := assert.New(t)
+
+	original := manioc.SetGlobalContainer(manioc.NewContainer())
+	defer manioc.SetGlobalContainer(original)
+
+	assert.Nil(manioc.RegisterScoped[IService, Service]())
+	assert.Nil(manioc.RegisterTransientConstructor[*Service](func() *Service { return &Service{name: "bar"} }))
+	scoped := manioc.MustResolve[IService]()
+	transient := manioc.MustResolve[*Service]()
+	snapshot := manioc.SnapshotGlobal()
+
+	manioc.RestoreGlobal(snapshot)
+	// the instances cached in the container are restored, but the others are created again
+	assert.Same(scoped, manioc.MustResolve[IService]())
+	assert.NotSame(transient, manioc.MustResolve[*Service]())
+	// the scopes of the restored container create their own instances
+	scope, closeScope := manioc.OpenScope()
+	defer closeScope()
+	assert.NotSame(scoped, manioc.MustResolve[IService](manioc.WithScope(scope)))
+}
+
+func Test_RestoreGlobal_Disposal(t *testing.T) {
+	assert := assert.New(t)
+
+	original := manioc.SetGlobalContainer(manioc.NewContainer())
+	defer manioc.SetGlobalContainer(original)
+
+	assert.Nil(manioc.RegisterSingletonConstructor[*DisposableService](
+		func() *DisposableService { return &DisposableService{} },
+	))
+	before := manioc.MustResolve[*DisposableService]()
+	snapshot := manioc.SnapshotGlobal()
+
+	// the restored container does not dispose the shared instances
+	assert.Nil(manioc.RestoreGlobal(snapshot).Close())
+	assert.Nil(manioc.GlobalContainer().Close())
+	assert.True(before.disposed)
+
+	// the instances created after restoring are disposed by the restored container
+	manioc.RestoreGlobal(snapshot)
+	before.disposed = false
+	assert.Same(before, manioc.MustResolve[*DisposableService]())
+	assert.Nil(manioc.GlobalContainer().Close())
+	assert.False(before.disposed)
+}
+
+func Test_SetGlobalContainer_Concurrency(t *testing.T) {
+	assert := assert.New(t)
+
+	original := manioc.SetGlobalContainer(manioc.NewContainer())
+	defer manioc.SetGlobalContainer(original)
+	assert.Nil(manioc.RegisterSingleton[IService, Service]())
+	snapshot := manioc.SnapshotGlobal()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				manioc.RestoreGlobal(snapshot)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := manioc.Resolve[IService]()
+				assert.Nil(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,206 @@
+package manioc_graph_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IFooService interface {
+	doFoo()
+}
+
+// FooService1 implements IFooService
+type FooService1 struct{}
+
+func (s *FooService1) doFoo() {}
+
+// FooService2 implements IFooService
+type FooService2 struct{}
+
+func (s *FooService2) doFoo() {}
+
+type IBarService interface {
+	doBar()
+}
+
+// BarService implements IBarService
+type BarService struct {
+	foo  IFooService   `manioc:"inject,key=foo"`
+	many []IFooService `manioc:"inject"`
+	baz  IBazService   `manioc:"inject,optional"`
+}
+
+func (s *BarService) doBar() {}
+
+type IBazService interface {
+	doBaz()
+}
+
+// BazService implements IBazService
+type BazService struct{}
+
+func (s *BazService) doBaz() {}
+
+func NewBazService(foo IFooService, bar manioc.Lazy[IBarService], qux IQuxService) *BazService {
+	return &BazService{}
+}
+
+type IQuxService interface {
+	doQux()
+}
+
+func newContainer(t *testing.T) manioc.Container {
+	t.Helper()
+	ctr := manioc.NewContainer()
+	assert.Nil(t, manioc.Register[IFooService, FooService1](manioc.WithContainer(ctr)))
+	assert.Nil(t, manioc.Register[IFooService, FooService2](manioc.WithContainer(ctr)))
+	assert.Nil(t, manioc.Register[IFooService, FooService1](manioc.WithContainer(ctr), manioc.WithRegisterKey("foo")))
+	assert.Nil(t, manioc.Register[IBarService, BarService](
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.GlobalCache),
+	))
+	assert.Nil(t, manioc.RegisterConstructor[IBazService](NewBazService, manioc.WithContainer(ctr)))
+	return ctr
+}
+
+func Test_Graph(t *testing.T) {
+	assert := assert.New(t)
+
+	g, err := manioc.Graph(newContainer(t))
+	assert.Nil(err)
+
+	// nodes are sorted by service type and key
+	assert.Len(g.Nodes, 5)
+	bar := g.Nodes[0]
+	assert.Equal("n0", bar.ID)
+	assert.Equal("manioc_graph_test.IBarService", bar.ServiceType)
+	assert.Equal("GlobalCache", bar.CachePolicy)
+	assert.Equal("type", bar.Kind)
+	assert.Equal("*manioc_graph_test.BarService", bar.Implementation)
+	assert.NotEmpty(bar.Site)
+	baz := g.Nodes[1]
+	assert.Equal("constructor", baz.Kind)
+	assert.Equal("NeverCache", baz.CachePolicy)
+	assert.Equal([]string{"n2", "n3"}, []string{g.Nodes[2].ID, g.Nodes[3].ID})
+	assert.Equal("", g.Nodes[2].ServiceKey)
+	assert.Equal("foo", g.Nodes[4].ServiceKey)
+
+	assert.Equal([]manioc.GraphEdge{
+		// BarService
+		{
+			From: "n0", To: []string{"n4"}, ServiceType: "manioc_graph_test.IFooService", ServiceKey: "foo",
+			ArgIndex: -1, FieldName: "foo", Optional: false, Status: manioc.EdgeResolved,
+		},
+		{
+			From: "n0", To: []string{"n2", "n3"}, ServiceType: "[]manioc_graph_test.IFooService",
+			ArgIndex: -1, FieldName: "many", Optional: false, Status: manioc.EdgeResolved,
+		},
+		{
+			From: "n0", To: []string{"n1"}, ServiceType: "manioc_graph_test.IBazService",
+			ArgIndex: -1, FieldName: "baz", Optional: true, Status: manioc.EdgeResolved,
+		},
+		// NewBazService
+		{
+			From: "n1", To: []string{"n2", "n3"}, ServiceType: "manioc_graph_test.IFooService",
+			ArgIndex: 0, Optional: false, Status: manioc.EdgeAmbiguous,
+		},
+		{
+			From: "n1", To: []string{"n0"}, ServiceType: "manioc.Lazy[github.com/fuzmish/manioc/tests/graph_test.IBarService]",
+			ArgIndex: 1, Optional: false, Status: manioc.EdgeResolved,
+		},
+		{
+			From: "n1", To: []string{}, ServiceType: "manioc_graph_test.IQuxService",
+			ArgIndex: 2, Optional: false, Status: manioc.EdgeUnresolved,
+		},
+	}, g.Edges)
+}
+
+func Test_Graph_JSON(t *testing.T) {
+	assert := assert.New(t)
+
+	g, err := manioc.Graph(newContainer(t))
+	assert.Nil(err)
+
+	var buf bytes.Buffer
+	assert.Nil(g.WriteJSON(&buf))
+
+	var decoded manioc.DependencyGraph
+	assert.Nil(json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(*g, decoded)
+	assert.Contains(buf.String(), `"status": "ambiguous"`)
+	assert.Contains(buf.String(), `"fieldName": "foo"`)
+}
+
+func Test_Graph_DOT(t *testing.T) {
+	assert := assert.New(t)
+
+	g, err := manioc.Graph(newContainer(t))
+	assert.Nil(err)
+
+	var buf bytes.Buffer
+	assert.Nil(g.WriteDOT(&buf))
+	dot := buf.String()
+
+	assert.Contains(dot, "digraph manioc {\n")
+	assert.Contains(dot, `"n0" [label="manioc_graph_test.IBarService\ntype: *manioc_graph_test.BarService\nGlobalCache"];`)
+	assert.Contains(
+		dot,
+		`"n4" [label="manioc_graph_test.IFooService\nkey=foo\ntype: *manioc_graph_test.FooService1\nNeverCache"];`,
+	)
+	assert.Contains(dot, `"n0" -> "n4" [label="foo"];`)
+	assert.Contains(dot, `"n1" -> "n2" [label="arg 0 (ambiguous)", color=orange, fontcolor=orange];`)
+	assert.Contains(dot, `"n1" -> "n3" [label="arg 0 (ambiguous)", color=orange, fontcolor=orange];`)
+	assert.Contains(dot, `"missing5" [label="manioc_graph_test.IQuxService", style=dashed, color=red, fontcolor=red];`)
+	assert.Contains(dot, `"n1" -> "missing5" [label="arg 2 (unresolved)", color=red, fontcolor=red];`)
+}
+
+func Test_Graph_OptionalMissing(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IBarService, BarService](manioc.WithContainer(ctr)))
+
+	g, err := manioc.Graph(ctr)
+	assert.Nil(err)
+	assert.Len(g.Nodes, 1)
+	assert.Len(g.Edges, 3)
+	assert.Equal(manioc.EdgeUnresolved, g.Edges[2].Status)
+	assert.True(g.Edges[2].Optional)
+
+	var buf bytes.Buffer
+	assert.Nil(g.WriteDOT(&buf))
+	assert.Contains(buf.String(), `"n0" -> "missing2" [label="baz (optional)", style=dashed, color=gray, fontcolor=gray];`)
+}
+
+// QuxService implements IQuxService
+type QuxService struct{}
+
+func (s *QuxService) doQux() {}
+
+func WithQux(inner IBazService, qux IQuxService) IBazService {
+	return inner
+}
+
+func Test_Graph_Decorator(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IBazService, BazService](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IQuxService, QuxService](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Decorate[IBazService](WithQux, manioc.WithContainer(ctr)))
+
+	// the dependencies of the decorators are included
+	g, err := manioc.Graph(ctr)
+	assert.Nil(err)
+	assert.Len(g.Nodes, 2)
+	assert.Equal([]manioc.GraphEdge{
+		{
+			From: "n0", To: []string{"n1"}, ServiceType: "manioc_graph_test.IQuxService",
+			ArgIndex: 1, Optional: false, Status: manioc.EdgeResolved,
+		},
+	}, g.Edges)
+}
@@ -0,0 +1,219 @@
+package manioc_httpx_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/fuzmish/manioc/httpx"
+	"github.com/stretchr/testify/assert"
+)
+
+type IGreeter interface {
+	greet() string
+}
+
+// Greeter implements IGreeter, and requires the request
+type Greeter struct {
+	req *http.Request `manioc:"inject"`
+}
+
+func (g *Greeter) greet() string {
+	return "hello, " + g.req.URL.Query().Get("name")
+}
+
+// GreetHandler is a handler which requires IGreeter and the response writer
+type GreetHandler struct {
+	greeter IGreeter            `manioc:"inject"`
+	w       http.ResponseWriter `manioc:"inject"`
+}
+
+func (h *GreetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.w.Header().Set("X-Greeter", "manioc")
+	fmt.Fprint(w, h.greeter.greet())
+}
+
+// Counter counts the instances created
+type Counter struct {
+	Value int
+}
+
+func setup(t *testing.T) manioc.Container {
+	t.Helper()
+	ctr := manioc.NewContainer()
+	assert.Nil(t, manioc.Register[IGreeter, Greeter](
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.ScopedCache),
+	))
+	assert.Nil(t, manioc.Register[*GreetHandler, *GreetHandler](manioc.WithContainer(ctr)))
+	return ctr
+}
+
+func get(t *testing.T, handler http.Handler, target string) *http.Response {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec.Result()
+}
+
+func body(t *testing.T, res *http.Response) string {
+	t.Helper()
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+func Test_Httpx_Handler(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := setup(t)
+	handler := httpx.Middleware(ctr)(httpx.Handler[*GreetHandler]())
+
+	res := get(t, handler, "/?name=alice")
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Equal("manioc", res.Header.Get("X-Greeter"))
+	assert.Equal("hello, alice", body(t, res))
+
+	// each request is handled within its own scope
+	res = get(t, handler, "/?name=bob")
+	assert.Equal("hello, bob", body(t, res))
+}
+
+func Test_Httpx_Resolve(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := setup(t)
+	var scopes []manioc.Scope
+	var greeters []IGreeter
+	handler := httpx.Middleware(ctr)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := httpx.ScopeFromRequest(r)
+		assert.True(ok)
+		scopes = append(scopes, scope)
+
+		// the scoped services are shared within the request
+		greeter, err := httpx.Resolve[IGreeter](r)
+		assert.Nil(err)
+		assert.Same(greeter, manioc.MustResolve[IGreeter](manioc.WithScope(scope)))
+		greeters = append(greeters, greeter)
+
+		// the request carrying the scope is registered
+		req, err := httpx.Resolve[*http.Request](r)
+		assert.Nil(err)
+		assert.Same(r, req)
+		fmt.Fprint(w, greeter.greet())
+	}))
+
+	assert.Equal("hello, alice", body(t, get(t, handler, "/?name=alice")))
+	assert.Equal("hello, bob", body(t, get(t, handler, "/?name=bob")))
+	assert.Len(scopes, 2)
+	assert.NotSame(scopes[0], scopes[1])
+	assert.NotSame(greeters[0], greeters[1])
+
+	// the scopes are closed after the requests
+	_, err := manioc.Resolve[IGreeter](manioc.WithScope(scopes[0]))
+	assert.ErrorIs(err, manioc.ErrScopeClosed)
+
+	// the request is not visible from the container
+	_, err = manioc.Resolve[IGreeter](manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrNotRegistered)
+}
+
+func Test_Httpx_Validate(t *testing.T) {
+	assert := assert.New(t)
+
+	// the request and the response writer are registered for the scopes of the requests
+	ctr := setup(t)
+	assert.ErrorIs(manioc.Validate(ctr), manioc.ErrNotRegistered)
+
+	// Middleware declares them on the container
+	httpx.Middleware(ctr)
+	assert.Nil(manioc.Validate(ctr))
+
+	// so that the singletons capturing them are reported
+	assert.Nil(manioc.Register[IGreeter, Greeter](
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.GlobalCache),
+		manioc.WithConflictPolicy(manioc.Replace),
+	))
+	assert.ErrorIs(manioc.Validate(ctr), manioc.ErrCaptiveDependency)
+
+	// the declarations are not made on the scopes
+	scoped := setup(t)
+	scope, closeScope := scoped.OpenScope()
+	defer closeScope()
+	httpx.Middleware(scope)
+	assert.ErrorIs(manioc.Validate(scoped), manioc.ErrNotRegistered)
+}
+
+func Test_Httpx_CacheMode(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := setup(t)
+	assert.Nil(manioc.Register[*Counter, *Counter](manioc.WithContainer(ctr), manioc.WithCachePolicy(manioc.ScopedCache)))
+	parent, closeParent := ctr.OpenScope()
+	defer closeParent()
+	counter := manioc.MustResolve[*Counter](manioc.WithScope(parent))
+
+	// the request scopes inherit the cache of the parent scope
+	handler := httpx.Middleware(parent, httpx.WithCacheMode(manioc.InheritCacheMode))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ret, err := httpx.Resolve[*Counter](r)
+			assert.Nil(err)
+			assert.Same(counter, ret)
+		}),
+	)
+	assert.Equal(http.StatusOK, get(t, handler, "/").StatusCode)
+}
+
+func Test_Httpx_SyncCacheMode(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := setup(t)
+	parent, closeParent := ctr.OpenScope()
+	defer closeParent()
+
+	// each request registers its own request and response writer, while sharing the cache with the parent
+	handler := httpx.Middleware(parent, httpx.WithCacheMode(manioc.SyncCacheMode))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req, err := httpx.Resolve[*http.Request](r)
+			assert.Nil(err)
+			assert.Same(r, req)
+			_, err = httpx.Resolve[http.ResponseWriter](r)
+			assert.Nil(err)
+			fmt.Fprint(w, req.URL.Query().Get("name"))
+		}),
+	)
+	assert.Equal("alice", body(t, get(t, handler, "/?name=alice")))
+	assert.Equal("bob", body(t, get(t, handler, "/?name=bob")))
+
+	// the requests are not left in the parent scope
+	_, err := manioc.Resolve[*http.Request](manioc.WithScope(parent))
+	assert.ErrorIs(err, manioc.ErrNotRegistered)
+}
+
+func Test_Httpx_Errors(t *testing.T) {
+	t.Run("resolution failure", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := manioc.NewContainer()
+		handler := httpx.Middleware(ctr)(httpx.Handler[*GreetHandler]())
+		assert.Equal(http.StatusInternalServerError, get(t, handler, "/").StatusCode)
+	})
+
+	t.Run("closed parent", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := setup(t)
+		called := false
+		handler := httpx.Middleware(ctr)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		assert.Nil(ctr.Close())
+		assert.Equal(http.StatusInternalServerError, get(t, handler, "/").StatusCode)
+		assert.False(called)
+	})
+}
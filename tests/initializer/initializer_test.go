@@ -0,0 +1,188 @@
+package manioc_initializer_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IGreeter interface {
+	Greet() string
+}
+
+// Config is a dependency of Greeter
+type Config struct {
+	Name string
+}
+
+// Greeter implements IGreeter and Initializer
+type Greeter struct {
+	config   *Config `manioc:"inject"`
+	greeting string
+	inits    int
+	failure  error
+}
+
+func (g *Greeter) Init() error {
+	if g.failure != nil {
+		return g.failure
+	}
+	// the fields are injected before Init
+	g.greeting = "hello " + g.config.Name
+	g.inits++
+	return nil
+}
+
+func (g *Greeter) Greet() string {
+	return g.greeting
+}
+
+// ExclaimingGreeter decorates IGreeter
+type ExclaimingGreeter struct {
+	inner IGreeter
+}
+
+func (g *ExclaimingGreeter) Greet() string {
+	return g.inner.Greet() + "!"
+}
+
+func Test_Initializer(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(&Config{Name: "world"}, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterSingleton[IGreeter, Greeter](manioc.WithContainer(ctr)))
+	// decorators receive the initialized instance
+	assert.Nil(manioc.Decorate[IGreeter](func(inner IGreeter) IGreeter {
+		assert.Equal("hello world", inner.Greet())
+		return &ExclaimingGreeter{inner: inner}
+	}, manioc.WithContainer(ctr)))
+
+	ret := manioc.MustResolve[IGreeter](manioc.WithScope(ctr))
+	assert.Equal("hello world!", ret.Greet())
+
+	// the cached instance is not initialized again
+	assert.Same(ret, manioc.MustResolve[IGreeter](manioc.WithScope(ctr)))
+	assert.Equal(1, ret.(*ExclaimingGreeter).inner.(*Greeter).inits)
+}
+
+func Test_Initializer_Constructor(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(&Config{Name: "world"}, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterConstructor[IGreeter](func() *Greeter { return &Greeter{} }, manioc.WithContainer(ctr)))
+
+	// the fields are injected into the instance returned by the constructor, and then it is initialized
+	ret := manioc.MustResolve[IGreeter](manioc.WithScope(ctr))
+	assert.Equal("hello world", ret.Greet())
+	assert.Equal(1, ret.(*Greeter).inits)
+}
+
+func Test_Initializer_Instance(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(&Config{Name: "world"}, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterInstance[IGreeter](&Greeter{greeting: "hi"}, manioc.WithContainer(ctr)))
+
+	// the instances given by the user are not initialized
+	ret := manioc.MustResolve[IGreeter](manioc.WithScope(ctr))
+	assert.Equal("hi", ret.Greet())
+	assert.Equal(0, ret.(*Greeter).inits)
+}
+
+func Test_Initializer_Failure(t *testing.T) {
+	assert := assert.New(t)
+
+	errInit := errors.New("failed to initialize")
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(&Config{Name: "world"}, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterSingletonConstructor[IGreeter](
+		func() *Greeter { return &Greeter{failure: errInit} },
+		manioc.WithContainer(ctr),
+	))
+
+	_, err := manioc.Resolve[IGreeter](manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrInitializerFailed)
+	assert.ErrorIs(err, errInit)
+	var resolveErr *manioc.ResolveError
+	assert.ErrorAs(err, &resolveErr)
+	assert.Equal(reflect.TypeOf((*IGreeter)(nil)).Elem(), resolveErr.ServiceType)
+}
+
+func Test_OnActivated(t *testing.T) {
+	assert := assert.New(t)
+
+	events := []string{}
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterInstance(&Config{Name: "world"}, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterScoped[IGreeter, Greeter](
+		manioc.WithContainer(ctr),
+		// called in the order of the options, after Init
+		manioc.WithOnActivated(func(g *Greeter) error {
+			events = append(events, "first: "+g.Greet())
+			return nil
+		}),
+		manioc.WithOnActivated(func(g IGreeter) error {
+			events = append(events, "second: "+g.Greet())
+			return nil
+		}),
+	))
+
+	ret := manioc.MustResolve[IGreeter](manioc.WithScope(ctr))
+	assert.Same(ret, manioc.MustResolve[IGreeter](manioc.WithScope(ctr)))
+	assert.Equal([]string{"first: hello world", "second: hello world"}, events)
+
+	// the instances given by the user are passed as well
+	config := &Config{Name: "foo"}
+	assert.Nil(manioc.RegisterInstance(config, manioc.WithContainer(ctr), manioc.WithRegisterKey("foo"),
+		manioc.WithOnActivated(func(c *Config) error {
+			c.Name += "!"
+			return nil
+		}),
+	))
+	assert.Equal("foo!", manioc.MustResolve[*Config](manioc.WithScope(ctr), manioc.WithResolveKey("foo")).Name)
+	assert.Equal("foo!", manioc.MustResolve[*Config](manioc.WithScope(ctr), manioc.WithResolveKey("foo")).Name)
+}
+
+func Test_OnActivated_Errors(t *testing.T) {
+	t.Run("function returns error", func(t *testing.T) {
+		assert := assert.New(t)
+
+		errHook := errors.New("failed to activate")
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[*Config, *Config](
+			manioc.WithContainer(ctr),
+			manioc.WithOnActivated(func(*Config) error { return errHook }),
+		))
+
+		_, err := manioc.Resolve[*Config](manioc.WithScope(ctr))
+		assert.ErrorIs(err, manioc.ErrInitializerFailed)
+		assert.ErrorIs(err, errHook)
+	})
+
+	t.Run("mismatched type", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[*Config, *Config](
+			manioc.WithContainer(ctr),
+			manioc.WithOnActivated(func(*Greeter) error { return nil }),
+		))
+
+		_, err := manioc.Resolve[*Config](manioc.WithScope(ctr))
+		assert.ErrorIs(err, manioc.ErrInitializerFailed)
+	})
+
+	t.Run("nil function", func(t *testing.T) {
+		assert := assert.New(t)
+
+		assert.Panics(func() {
+			manioc.WithOnActivated[*Config](nil)
+		})
+	})
+}
@@ -0,0 +1,305 @@
+package manioc_lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+// Recorder records the lifecycle events
+type Recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *Recorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *Recorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.events...)
+}
+
+// Database implements Starter and Stopper
+type Database struct {
+	rec     *Recorder `manioc:"inject"`
+	failure error
+}
+
+func (d *Database) Start(ctx context.Context) error {
+	d.rec.record("start db")
+	return nil
+}
+
+func (d *Database) Stop(ctx context.Context) error {
+	d.rec.record("stop db")
+	return d.failure
+}
+
+type IServer interface {
+	Serve()
+}
+
+// Server implements Starter and Stopper, and requires Database
+type Server struct {
+	rec     *Recorder `manioc:"inject"`
+	db      *Database `manioc:"inject"`
+	failure error
+}
+
+func (s *Server) Serve() {}
+
+func (s *Server) Start(ctx context.Context) error {
+	s.rec.record("start server")
+	return s.failure
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	s.rec.record("stop server")
+	return nil
+}
+
+// Worker implements Starter only
+type Worker struct {
+	rec *Recorder `manioc:"inject"`
+}
+
+func (w *Worker) Start(ctx context.Context) error {
+	w.rec.record("start worker")
+	return nil
+}
+
+func setup(t *testing.T, serverFailure error) (manioc.Container, *Recorder) {
+	t.Helper()
+	ctr := manioc.NewContainer()
+	rec := &Recorder{}
+	assert.Nil(t, manioc.RegisterInstance(rec, manioc.WithContainer(ctr)))
+	// the server is registered before the database, but it is started after the database
+	assert.Nil(t, manioc.RegisterConstructor[IServer](func(rec *Recorder, db *Database) IServer {
+		return &Server{rec: rec, db: db, failure: serverFailure}
+	}, manioc.WithContainer(ctr), manioc.WithCachePolicy(manioc.GlobalCache)))
+	assert.Nil(t, manioc.Register[*Database, *Database](
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.GlobalCache),
+	))
+	// non-singletons are not started
+	assert.Nil(t, manioc.Register[*Worker, *Worker](manioc.WithContainer(ctr), manioc.WithCachePolicy(manioc.ScopedCache)))
+	assert.Nil(t, manioc.RegisterHook(manioc.Hook{
+		Name:    "hook",
+		OnStart: func(ctx context.Context) error { rec.record("start hook"); return nil },
+		OnStop:  func(ctx context.Context) error { rec.record("stop hook"); return nil },
+	}, manioc.WithContainer(ctr)))
+	return ctr, rec
+}
+
+func Test_Lifecycle(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr, rec := setup(t, nil)
+	assert.Nil(manioc.Stop(context.Background(), ctr))
+	assert.Nil(manioc.Start(context.Background(), ctr))
+	assert.Equal([]string{"start db", "start server", "start hook"}, rec.Events())
+
+	// the started singletons are the ones resolved
+	server := manioc.MustResolve[IServer](manioc.WithScope(ctr))
+	assert.Same(manioc.MustResolve[*Database](manioc.WithScope(ctr)), server.(*Server).db)
+
+	// started only once
+	assert.ErrorIs(manioc.Start(context.Background(), ctr), manioc.ErrAlreadyStarted)
+
+	assert.Nil(manioc.Stop(context.Background(), ctr))
+	assert.Equal([]string{
+		"start db", "start server", "start hook",
+		"stop hook", "stop server", "stop db",
+	}, rec.Events())
+
+	// stopped only once
+	assert.Nil(manioc.Stop(context.Background(), ctr))
+	assert.Len(rec.Events(), 6)
+}
+
+func Test_Lifecycle_StartFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	errServer := errors.New("server failed")
+	ctr, rec := setup(t, errServer)
+	err := manioc.Start(context.Background(), ctr)
+	assert.ErrorIs(err, errServer)
+	assert.Contains(err.Error(), "failed to start `*manioc_lifecycle_test.Server`")
+
+	// the started ones are stopped in reverse order
+	assert.Equal([]string{"start db", "start server", "stop db"}, rec.Events())
+	assert.Nil(manioc.Stop(context.Background(), ctr))
+	assert.Len(rec.Events(), 3)
+}
+
+func Test_Lifecycle_StopFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	errDatabase := errors.New("database failed")
+	errHook := errors.New("hook failed")
+	ctr, rec := setup(t, nil)
+	assert.Nil(manioc.RegisterHook(manioc.Hook{
+		OnStop: func(ctx context.Context) error { return errHook },
+	}, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Start(context.Background(), ctr))
+	manioc.MustResolve[*Database](manioc.WithScope(ctr)).failure = errDatabase
+
+	// all of them are stopped, and the errors are aggregated
+	err := manioc.Stop(context.Background(), ctr)
+	assert.ErrorIs(err, errDatabase)
+	assert.ErrorIs(err, errHook)
+	var aggregated *manioc.AggregateError
+	assert.ErrorAs(err, &aggregated)
+	assert.Len(aggregated.Errors, 2)
+	assert.Equal("failed to stop `hook`: hook failed", aggregated.Errors[0].Error())
+	assert.Equal([]string{
+		"start db", "start server", "start hook",
+		"stop hook", "stop server", "stop db",
+	}, rec.Events())
+}
+
+func Test_Lifecycle_Timeout(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr, rec := setup(t, nil)
+	assert.Nil(manioc.RegisterHook(manioc.Hook{
+		Name: "slow",
+		OnStart: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}, manioc.WithContainer(ctr)))
+
+	err := manioc.Start(context.Background(), ctr, manioc.WithStartTimeout(10*time.Millisecond))
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Contains(err.Error(), "failed to start `slow`")
+	assert.Equal([]string{
+		"start db", "start server", "start hook",
+		"stop hook", "stop server", "stop db",
+	}, rec.Events())
+}
+
+func Test_Lifecycle_HookInConstructor(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	rec := &Recorder{}
+	assert.Nil(manioc.RegisterInstance(rec, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterConstructor[*Worker](func(rec *Recorder) *Worker {
+		// hooks can be registered while starting
+		assert.Nil(manioc.RegisterHook(manioc.Hook{
+			OnStart: func(ctx context.Context) error { rec.record("start hook"); return nil },
+		}, manioc.WithContainer(ctr)))
+		return &Worker{rec: rec}
+	}, manioc.WithContainer(ctr), manioc.WithCachePolicy(manioc.GlobalCache)))
+
+	assert.Nil(manioc.Start(context.Background(), ctr))
+	assert.Equal([]string{"start worker", "start hook"}, rec.Events())
+	assert.Nil(manioc.Stop(context.Background(), ctr))
+}
+
+func Test_Lifecycle_ActivationFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[*Worker, *Worker](manioc.WithContainer(ctr), manioc.WithCachePolicy(manioc.GlobalCache)))
+	err := manioc.Start(context.Background(), ctr)
+	assert.ErrorIs(err, manioc.ErrNotRegistered)
+
+	// it can be started after fixing the registrations
+	rec := &Recorder{}
+	assert.Nil(manioc.RegisterInstance(rec, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Start(context.Background(), ctr))
+	assert.Equal([]string{"start worker"}, rec.Events())
+}
+
+// Listener implements Starter, and keeps the injected context.Context
+type Listener struct {
+	ctx context.Context
+}
+
+func (l *Listener) Start(ctx context.Context) error {
+	return nil
+}
+
+func Test_Lifecycle_InjectedContext(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterSingletonConstructor[*Listener](
+		func(ctx context.Context) *Listener { return &Listener{ctx: ctx} },
+		manioc.WithContainer(ctr),
+	))
+	assert.Nil(manioc.Start(context.Background(), ctr, manioc.WithStartTimeout(time.Second)))
+	defer func() { assert.Nil(manioc.Stop(context.Background(), ctr)) }()
+
+	// the singleton activated by Start outlives the start, so its context.Context is still live
+	listener := manioc.MustResolve[*Listener](manioc.WithScope(ctr))
+	assert.Nil(listener.ctx.Err())
+	_, ok := listener.ctx.Deadline()
+	assert.False(ok)
+}
+
+func Test_Lifecycle_Run(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr, rec := setup(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Nil(manioc.RegisterHook(manioc.Hook{
+		OnStart: func(context.Context) error {
+			// stop running after started
+			cancel()
+			return nil
+		},
+	}, manioc.WithContainer(ctr)))
+
+	assert.Nil(manioc.Run(ctx, ctr, manioc.WithStopTimeout(time.Second)))
+	assert.Equal([]string{
+		"start db", "start server", "start hook",
+		"stop hook", "stop server", "stop db",
+	}, rec.Events())
+}
+
+func Test_Lifecycle_RunSignalDuringStart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sending os.Interrupt is not implemented on windows")
+	}
+	assert := assert.New(t)
+
+	ctr, rec := setup(t, nil)
+	assert.Nil(manioc.RegisterHook(manioc.Hook{
+		Name: "slow",
+		OnStart: func(ctx context.Context) error {
+			// the signal arrives during a slow start
+			process, err := os.FindProcess(os.Getpid())
+			if err != nil {
+				return err
+			}
+			if err := process.Signal(os.Interrupt); err != nil {
+				return err
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}, manioc.WithContainer(ctr)))
+
+	// the start is canceled, and the ones already started are stopped
+	err := manioc.Run(context.Background(), ctr, manioc.WithStopTimeout(time.Second))
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal([]string{
+		"start db", "start server", "start hook",
+		"stop hook", "stop server", "stop db",
+	}, rec.Events())
+}
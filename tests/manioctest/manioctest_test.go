@@ -0,0 +1,154 @@
+package manioc_manioctest_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/fuzmish/manioc/manioctest"
+	"github.com/stretchr/testify/assert"
+)
+
+type IMailer interface {
+	Send(to string) error
+}
+
+// Mailer implements IMailer
+type Mailer struct{}
+
+func (m *Mailer) Send(to string) error {
+	return errors.New("not available in tests")
+}
+
+// FakeMailer implements IMailer
+type FakeMailer struct {
+	sent []string
+}
+
+func (m *FakeMailer) Send(to string) error {
+	m.sent = append(m.sent, to)
+	return nil
+}
+
+// SignupService depends on IMailer
+type SignupService struct {
+	mailer IMailer `manioc:"inject"`
+}
+
+func (s *SignupService) Signup(user string) error {
+	return s.mailer.Send(user)
+}
+
+func newContainer(t *testing.T) manioc.Container {
+	t.Helper()
+	ctr := manioc.NewContainer()
+	assert.Nil(t, manioc.RegisterSingleton[IMailer, Mailer](manioc.WithContainer(ctr)))
+	assert.Nil(t, manioc.RegisterSingleton[*SignupService, *SignupService](manioc.WithContainer(ctr)))
+	return ctr
+}
+
+func Test_Override(t *testing.T) {
+	ctr := newContainer(t)
+	// the singleton is already created in the container
+	service := manioc.MustResolve[*SignupService](manioc.WithScope(ctr))
+	assert.Error(t, service.Signup("foo"))
+
+	for _, user := range []string{"foo", "bar", "baz"} {
+		user := user
+		t.Run(user, func(t *testing.T) {
+			t.Parallel()
+			assert := assert.New(t)
+
+			// the services depending on the fake are created again in the overridden container
+			fake := &FakeMailer{}
+			overridden, restore := manioctest.Override[IMailer](t, ctr, fake)
+			service := manioc.MustResolve[*SignupService](manioc.WithScope(overridden))
+			assert.Nil(service.Signup(user))
+			assert.Equal([]string{user}, fake.sent)
+
+			// the site of the registration is the test
+			for _, r := range manioc.Registrations(overridden) {
+				if r.Kind == manioc.InstanceRegistration {
+					assert.True(strings.Contains(r.Site, "manioctest_test.go"), r.Site)
+				}
+			}
+
+			restore()
+			_, err := manioc.Resolve[*SignupService](manioc.WithScope(overridden))
+			assert.ErrorIs(err, manioc.ErrScopeClosed)
+			restore()
+		})
+	}
+
+	// the container is not affected
+	assert.Same(t, service, manioc.MustResolve[*SignupService](manioc.WithScope(ctr)))
+	assert.IsType(t, &Mailer{}, manioc.MustResolve[IMailer](manioc.WithScope(ctr)))
+}
+
+func Test_Override_Constructor(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := newContainer(t)
+	overridden, _ := manioctest.Override[IMailer](t, ctr, func() *FakeMailer {
+		return &FakeMailer{}
+	}, manioc.WithCachePolicy(manioc.NeverCache))
+	counter := manioctest.CountActivations[IMailer](t, overridden)
+
+	// the constructor is used with the given cache policy
+	first := manioc.MustResolve[IMailer](manioc.WithScope(overridden))
+	second := manioc.MustResolve[IMailer](manioc.WithScope(overridden))
+	assert.IsType(&FakeMailer{}, first)
+	assert.NotSame(first, second)
+	assert.True(manioctest.AssertActivated(t, counter, 2))
+
+	// the singletons are activated once, however many times they are resolved
+	singletons := manioctest.CountActivations[*SignupService](t, overridden)
+	manioc.MustResolve[*SignupService](manioc.WithScope(overridden))
+	manioc.MustResolve[*SignupService](manioc.WithScope(overridden))
+	assert.True(manioctest.AssertActivated(t, singletons, 1))
+	assert.Equal(3, counter.Count())
+
+	// report the mismatch
+	mock := &testing.T{}
+	assert.False(manioctest.AssertActivated(mock, counter, 1))
+	assert.True(mock.Failed())
+}
+
+func Test_CountResolutions(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioctest.Isolate(t, newContainer(t))
+	mailers := manioctest.CountResolutions[IMailer](t, ctr)
+	services := manioctest.CountResolutions[*SignupService](t, ctr)
+	activations := manioctest.CountActivations[*SignupService](t, ctr)
+
+	// the resolutions served from the caches are counted, as well as the injected ones
+	manioc.MustResolve[*SignupService](manioc.WithScope(ctr))
+	manioc.MustResolve[*SignupService](manioc.WithScope(ctr))
+	manioc.MustResolve[IMailer](manioc.WithScope(ctr))
+	assert.True(manioctest.AssertResolved(t, services, 2))
+	assert.True(manioctest.AssertResolved(t, mailers, 2))
+	assert.True(manioctest.AssertActivated(t, activations, 1))
+
+	// report the mismatch
+	mock := &testing.T{}
+	assert.False(manioctest.AssertResolved(mock, services, 1))
+	assert.True(mock.Failed())
+}
+
+func Test_Isolate(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := newContainer(t)
+	var isolated manioc.Container
+	t.Run("isolated", func(t *testing.T) {
+		isolated = manioctest.Isolate(t, ctr)
+		assert.True(manioc.Unregister[IMailer](manioc.WithContainer(isolated)))
+		assert.False(manioc.IsRegistered[IMailer](manioc.WithContainer(isolated)))
+	})
+	// closed after the test
+	_, err := manioc.Resolve[*SignupService](manioc.WithScope(isolated))
+	assert.ErrorIs(err, manioc.ErrScopeClosed)
+	assert.True(manioc.IsRegistered[IMailer](manioc.WithContainer(ctr)))
+}
@@ -0,0 +1,143 @@
+package manioc_module_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IFooService interface {
+	doFoo()
+}
+
+// FooService implements IFooService
+type FooService struct{}
+
+func (s *FooService) doFoo() {}
+
+type IBarService interface {
+	doBar()
+}
+
+// BarService implements IBarService
+type BarService struct {
+	foo IFooService `manioc:"inject"`
+}
+
+func (s *BarService) doBar() {}
+
+func newModules(calls *[]string) (*manioc.Module, *manioc.Module, *manioc.Module) {
+	foo := manioc.NewModule("foo", func(ctr manioc.Container) error {
+		*calls = append(*calls, "foo")
+		return manioc.Register[IFooService, FooService](manioc.WithContainer(ctr))
+	})
+	bar := manioc.NewModule("bar", func(ctr manioc.Container) error {
+		*calls = append(*calls, "bar")
+		return manioc.Register[IBarService, BarService](manioc.WithContainer(ctr))
+	}, foo)
+	app := manioc.NewModule("app", func(ctr manioc.Container) error {
+		*calls = append(*calls, "app")
+		return nil
+	}, foo, bar)
+	return foo, bar, app
+}
+
+func Test_Module_Install(t *testing.T) {
+	assert := assert.New(t)
+
+	calls := make([]string, 0)
+	_, bar, app := newModules(&calls)
+	assert.Equal("app", app.Name())
+	assert.Len(app.Requires(), 2)
+
+	ctr := manioc.NewContainer()
+	assert.Empty(manioc.InstalledModules(ctr))
+
+	// the required modules are installed first, and only once
+	assert.Nil(manioc.Install(ctr, app))
+	assert.Equal([]string{"foo", "bar", "app"}, calls)
+	assert.Equal([]string{"foo", "bar", "app"}, manioc.InstalledModules(ctr))
+
+	ret, err := manioc.Resolve[IBarService](manioc.WithScope(ctr))
+	assert.Nil(err)
+	assert.NotNil(ret)
+
+	// explicit duplicate installations are detected
+	err = manioc.Install(ctr, bar)
+	assert.ErrorIs(err, manioc.ErrModuleAlreadyInstalled)
+	assert.Contains(err.Error(), "bar")
+	assert.Len(calls, 3)
+
+	// modules are installed per container
+	ctr2 := manioc.NewContainer()
+	assert.Nil(manioc.Install(ctr2, bar))
+	assert.Equal([]string{"foo", "bar"}, manioc.InstalledModules(ctr2))
+}
+
+func Test_Module_NameConflict(t *testing.T) {
+	assert := assert.New(t)
+
+	calls := make([]string, 0)
+	foo, _, _ := newModules(&calls)
+	anotherFoo := manioc.NewModule("foo", func(ctr manioc.Container) error { return nil })
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Install(ctr, foo))
+	err := manioc.Install(ctr, anotherFoo)
+	assert.ErrorIs(err, manioc.ErrModuleAlreadyInstalled)
+
+	// also detected for required modules
+	requiresAnotherFoo := manioc.NewModule("baz", func(ctr manioc.Container) error { return nil }, anotherFoo)
+	err = manioc.Install(ctr, requiresAnotherFoo)
+	assert.ErrorIs(err, manioc.ErrModuleAlreadyInstalled)
+	assert.Contains(err.Error(), "baz")
+	assert.Equal([]string{"foo"}, manioc.InstalledModules(ctr))
+}
+
+func Test_Module_Errors(t *testing.T) {
+	t.Run("setup error names the module", func(t *testing.T) {
+		assert := assert.New(t)
+
+		errSetup := errors.New("setup failed")
+		broken := manioc.NewModule("broken", func(ctr manioc.Container) error { return errSetup })
+		app := manioc.NewModule("app", func(ctr manioc.Container) error { return nil }, broken)
+
+		ctr := manioc.NewContainer()
+		err := manioc.Install(ctr, app)
+		assert.ErrorIs(err, errSetup)
+		assert.Equal("failed to install module `app`: failed to install module `broken`: setup failed", err.Error())
+
+		// failed modules are not marked as installed
+		assert.Empty(manioc.InstalledModules(ctr))
+	})
+
+	t.Run("circular requirements", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var a *manioc.Module
+		b := manioc.NewModule("b", func(ctr manioc.Container) error {
+			// install a recursively; this is detected as duplicated
+			return manioc.Install(ctr, a)
+		})
+		a = manioc.NewModule("a", func(ctr manioc.Container) error { return nil }, b)
+
+		ctr := manioc.NewContainer()
+		err := manioc.Install(ctr, a)
+		assert.ErrorIs(err, manioc.ErrModuleAlreadyInstalled)
+		assert.Empty(manioc.InstalledModules(ctr))
+
+		// requirement cycle
+		c := manioc.NewModule("c", func(ctr manioc.Container) error { return nil })
+		d := manioc.NewModule("d", func(ctr manioc.Container) error { return nil }, c)
+		e := manioc.NewModule("c", func(ctr manioc.Container) error { return nil }, d)
+		err = manioc.Install(ctr, e)
+		assert.Error(err)
+		assert.Contains(err.Error(), "circular requirement: c -> d -> c")
+	})
+
+	t.Run("nil setup function", func(t *testing.T) {
+		assert.Panics(t, func() { manioc.NewModule("nil", nil) })
+	})
+}
@@ -0,0 +1,139 @@
+package manioc_optional_injection_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IFooService interface {
+	doFoo()
+}
+
+// FooService implements IFooService
+type FooService struct {
+	bar IBarService `manioc:"inject"`
+}
+
+func (s *FooService) doFoo() {}
+
+type IBarService interface {
+	doBar()
+}
+
+// BarService implements IBarService
+type BarService struct{}
+
+func (s *BarService) doBar() {}
+
+// MyService requires IFooService optionally
+type MyService struct {
+	fooByField    IFooService                  `manioc:"inject,optional"`
+	fooByKey      IFooService                  `manioc:"inject,optional,key=foo"`
+	fooByOptional manioc.Optional[IFooService] `manioc:"inject"`
+	fooByArg      manioc.Optional[IFooService]
+}
+
+func NewMyService(foo manioc.Optional[IFooService]) *MyService {
+	return &MyService{fooByArg: foo}
+}
+
+func Test_Optional_NotRegistered(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterConstructor[*MyService](NewMyService, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Validate(ctr))
+
+	ret, err := manioc.Resolve[*MyService](manioc.WithScope(ctr))
+	assert.Nil(err)
+	assert.Nil(ret.fooByField)
+	assert.Nil(ret.fooByKey)
+	_, ok := ret.fooByOptional.Get()
+	assert.False(ok)
+	foo, ok := ret.fooByArg.Get()
+	assert.False(ok)
+	assert.Nil(foo)
+
+	// OrElse returns the fallback value
+	fallback := &FooService{}
+	assert.Same(fallback, ret.fooByArg.OrElse(fallback))
+
+	// Optional[T] can be resolved directly
+	opt := manioc.MustResolve[manioc.Optional[IFooService]](manioc.WithScope(ctr))
+	_, ok = opt.Get()
+	assert.False(ok)
+}
+
+func Test_Optional_Registered(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterSingleton[IFooService, FooService](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IBarService, BarService](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterConstructor[*MyService](NewMyService, manioc.WithContainer(ctr)))
+
+	ret, err := manioc.Resolve[*MyService](manioc.WithScope(ctr))
+	assert.Nil(err)
+	assert.NotNil(ret.fooByField)
+	// IFooService with key=foo is not registered
+	assert.Nil(ret.fooByKey)
+	foo, ok := ret.fooByOptional.Get()
+	assert.True(ok)
+	assert.Same(ret.fooByField, foo)
+	foo, ok = ret.fooByArg.Get()
+	assert.True(ok)
+	assert.Same(ret.fooByField, foo)
+	assert.Same(foo, ret.fooByArg.OrElse(nil))
+}
+
+func Test_Optional_Errors(t *testing.T) {
+	t.Run("missing nested dependencies are not ignored", func(t *testing.T) {
+		assert := assert.New(t)
+
+		// IFooService is registered, but its dependency IBarService is not
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[IFooService, FooService](manioc.WithContainer(ctr)))
+		assert.Nil(manioc.RegisterConstructor[*MyService](NewMyService, manioc.WithContainer(ctr)))
+
+		_, err := manioc.Resolve[*MyService](manioc.WithScope(ctr))
+		assert.ErrorIs(err, manioc.ErrNotRegistered)
+		var resolveErr *manioc.ResolveError
+		assert.ErrorAs(err, &resolveErr)
+		assert.Equal("manioc_optional_injection_test.IBarService", resolveErr.ServiceType.String())
+
+		err = manioc.Validate(ctr)
+		assert.ErrorIs(err, manioc.ErrNotRegistered)
+	})
+
+	t.Run("activation errors are propagated", func(t *testing.T) {
+		assert := assert.New(t)
+
+		errFoo := errors.New("failed to create foo")
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.RegisterConstructor[IFooService](
+			func() (*FooService, error) { return nil, errFoo },
+			manioc.WithContainer(ctr),
+		))
+		assert.Nil(manioc.RegisterConstructor[*MyService](NewMyService, manioc.WithContainer(ctr)))
+
+		_, err := manioc.Resolve[*MyService](manioc.WithScope(ctr))
+		assert.ErrorIs(err, manioc.ErrConstructorFailed)
+		assert.ErrorIs(err, errFoo)
+	})
+
+	t.Run("ambiguous registrations are not ignored", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[IBarService, BarService](manioc.WithContainer(ctr)))
+		assert.Nil(manioc.Register[IFooService, FooService](manioc.WithContainer(ctr)))
+		assert.Nil(manioc.Register[IFooService, FooService](manioc.WithContainer(ctr)))
+		assert.Nil(manioc.RegisterConstructor[*MyService](NewMyService, manioc.WithContainer(ctr)))
+
+		_, err := manioc.Resolve[*MyService](manioc.WithScope(ctr))
+		assert.ErrorIs(err, manioc.ErrAmbiguous)
+	})
+}
@@ -0,0 +1,123 @@
+package manioc_registrations_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type IFooService interface {
+	doFoo()
+}
+
+// FooService implements IFooService
+type FooService struct{}
+
+func (s *FooService) doFoo() {}
+
+type IBarService interface {
+	doBar()
+}
+
+// BarService implements IBarService
+type BarService struct{}
+
+func (s *BarService) doBar() {}
+
+func NewBarService(foo IFooService) *BarService {
+	return &BarService{}
+}
+
+// Returns `file:line` of the caller, with the line offset.
+func site(offset int) string {
+	_, file, line, _ := runtime.Caller(1)
+	return file + ":" + strconv.Itoa(line+offset)
+}
+
+func Test_Registrations(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Empty(manioc.Registrations(ctr))
+
+	// register
+	withCtr := manioc.WithContainer(ctr)
+	assert.Nil(manioc.Register[IFooService, FooService](withCtr, manioc.WithCachePolicy(manioc.ScopedCache)))
+	fooSite := site(-1)
+	assert.Nil(manioc.RegisterConstructor[IBarService](NewBarService, withCtr, manioc.WithRegisterKey("bar")))
+	barSite := site(-1)
+	instance := &BarService{}
+	assert.Nil(manioc.RegisterInstance[IBarService](instance, withCtr))
+	instanceSite := site(-1)
+
+	// sorted by service type and key
+	regs := manioc.Registrations(ctr)
+	assert.Len(regs, 3)
+
+	bar := regs[0]
+	assert.Equal(reflect.TypeOf((*IBarService)(nil)).Elem(), bar.ServiceType)
+	assert.Nil(bar.ServiceKey)
+	assert.Equal(manioc.GlobalCache, bar.CachePolicy)
+	assert.Equal(manioc.InstanceRegistration, bar.Kind)
+	assert.Equal(reflect.TypeOf(instance), bar.Implementation)
+	assert.Equal(instanceSite, bar.Site)
+
+	keyedBar := regs[1]
+	assert.Equal("bar", keyedBar.ServiceKey)
+	assert.Equal(manioc.NeverCache, keyedBar.CachePolicy)
+	assert.Equal(manioc.ConstructorRegistration, keyedBar.Kind)
+	assert.Equal(reflect.TypeOf(NewBarService), keyedBar.Implementation)
+	assert.Equal(
+		"func(manioc_registrations_test.IFooService) *manioc_registrations_test.BarService",
+		keyedBar.Implementation.String(),
+	)
+	assert.Equal(barSite, keyedBar.Site)
+
+	foo := regs[2]
+	assert.Equal(reflect.TypeOf((*IFooService)(nil)).Elem(), foo.ServiceType)
+	assert.Equal(manioc.ScopedCache, foo.CachePolicy)
+	assert.Equal(manioc.TypeRegistration, foo.Kind)
+	assert.Equal(reflect.TypeOf(&FooService{}), foo.Implementation)
+	assert.Equal(fooSite, foo.Site)
+	assert.Equal("registrations_test.go", filepath.Base(strings.Split(foo.Site, ":")[0]))
+
+	// the descriptions are snapshots
+	assert.True(manioc.Unregister[IFooService](manioc.WithContainer(ctr)))
+	assert.Len(regs, 3)
+	assert.Len(manioc.Registrations(ctr), 2)
+}
+
+func Test_Registrations_Module(t *testing.T) {
+	assert := assert.New(t)
+
+	var setupSite string
+	module := manioc.NewModule("foo", func(ctr manioc.Container) error {
+		err := manioc.Register[IFooService, FooService](manioc.WithContainer(ctr))
+		setupSite = site(-1)
+		return err
+	})
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Install(ctr, module))
+
+	// the site points to the setup function, not the internal of the library
+	regs := manioc.Registrations(ctr)
+	assert.Len(regs, 1)
+	assert.Equal(setupSite, regs[0].Site)
+}
+
+func Test_Registrations_String(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("type", manioc.TypeRegistration.String())
+	assert.Equal("constructor", manioc.ConstructorRegistration.String())
+	assert.Equal("instance", manioc.InstanceRegistration.String())
+	assert.Equal("GlobalCache", manioc.GlobalCache.String())
+	assert.Equal("ScopedCache", manioc.ScopedCache.String())
+	assert.Equal("NeverCache", manioc.NeverCache.String())
+}
@@ -0,0 +1,235 @@
+package manioc_resolve_context_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+type contextKey struct{}
+
+type IFooService interface {
+	doFoo()
+}
+
+// FooService implements IFooService, and receives context.Context via constructor
+type FooService struct {
+	ctx context.Context
+}
+
+func (s *FooService) doFoo() {}
+
+func NewFooService(ctx context.Context) *FooService {
+	return &FooService{ctx: ctx}
+}
+
+type IBarService interface {
+	doBar()
+}
+
+// BarService implements IBarService, and receives context.Context via field injection
+type BarService struct {
+	ctx context.Context `manioc:"inject"`
+	foo IFooService     `manioc:"inject"`
+}
+
+func (s *BarService) doBar() {}
+
+func setup(t *testing.T) manioc.Container {
+	t.Helper()
+	ctr := manioc.NewContainer()
+	assert.Nil(t, manioc.RegisterConstructor[IFooService](NewFooService, manioc.WithContainer(ctr)))
+	assert.Nil(t, manioc.Register[IBarService, BarService](manioc.WithContainer(ctr)))
+	return ctr
+}
+
+func Test_ResolveContext(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := setup(t)
+	scope, closeScope := ctr.OpenScope()
+	defer closeScope()
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	ret, err := manioc.ResolveContext[IBarService](ctx, manioc.WithScope(scope))
+	assert.Nil(err)
+	bar, ok := ret.(*BarService)
+	assert.True(ok)
+
+	// the context.Context is injected into constructors and fields
+	assert.Equal("value", bar.ctx.Value(contextKey{}))
+	foo, ok := bar.foo.(*FooService)
+	assert.True(ok)
+	assert.Equal("value", foo.ctx.Value(contextKey{}))
+
+	// the injected context.Context carries the scope
+	injectedScope, ok := manioc.ScopeFromContext(foo.ctx)
+	assert.True(ok)
+	assert.Same(scope, injectedScope)
+
+	// the scope carried by the context.Context is used by default
+	ret, err = manioc.ResolveContext[IBarService](bar.ctx)
+	assert.Nil(err)
+	injectedScope, ok = manioc.ScopeFromContext(ret.(*BarService).ctx)
+	assert.True(ok)
+	assert.Same(scope, injectedScope)
+
+	many, err := manioc.ResolveManyContext[IFooService](ctx, manioc.WithScope(ctr))
+	assert.Nil(err)
+	assert.Len(many, 1)
+	injectedScope, ok = manioc.ScopeFromContext(many[0].(*FooService).ctx)
+	assert.True(ok)
+	assert.Same(ctr, injectedScope)
+
+	// context.Context is not a missing dependency
+	assert.Nil(manioc.Validate(ctr))
+}
+
+func Test_ResolveContext_Resolve(t *testing.T) {
+	assert := assert.New(t)
+
+	// Resolve injects a context.Context which carries the scope as well
+	ctr := setup(t)
+	ret, err := manioc.Resolve[IFooService](manioc.WithScope(ctr))
+	assert.Nil(err)
+	foo, ok := ret.(*FooService)
+	assert.True(ok)
+	assert.NotNil(foo.ctx)
+	scope, ok := manioc.ScopeFromContext(foo.ctx)
+	assert.True(ok)
+	assert.Same(ctr, scope)
+
+	_, ok = manioc.ScopeFromContext(context.Background())
+	assert.False(ok)
+}
+
+func Test_ResolveContext_Singleton(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	var constructed context.Context
+	assert.Nil(manioc.RegisterConstructor[IFooService](
+		func(ctx context.Context) *FooService {
+			constructed = ctx
+			return &FooService{ctx: ctx}
+		},
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.GlobalCache),
+	))
+	assert.Nil(manioc.Register[IBarService, BarService](manioc.WithContainer(ctr)))
+	scope, closeScope := ctr.OpenScope()
+
+	// the singleton outlives the scope, so its context.Context carries the container which owns it
+	ret, err := manioc.ResolveContext[IBarService](context.Background(), manioc.WithScope(scope))
+	assert.Nil(err)
+	injectedScope, ok := manioc.ScopeFromContext(constructed)
+	assert.True(ok)
+	assert.Same(ctr, injectedScope)
+	// while the transient service resolved within the scope carries the scope
+	injectedScope, ok = manioc.ScopeFromContext(ret.(*BarService).ctx)
+	assert.True(ok)
+	assert.Same(scope, injectedScope)
+
+	closeScope()
+	_, err = manioc.ResolveContext[IBarService](constructed)
+	assert.Nil(err)
+}
+
+func Test_ResolveContext_Detached(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.RegisterConstructor[IFooService](NewFooService, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IBarService, BarService](
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.GlobalCache),
+	))
+
+	// e.g. the context.Context of the first request
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), contextKey{}, "value"))
+	ret, err := manioc.ResolveContext[IBarService](ctx, manioc.WithScope(ctr))
+	assert.Nil(err)
+	cancel()
+
+	// the singleton and its dependencies outlive the resolution, so their context.Context is not canceled
+	bar := ret.(*BarService)
+	foo := bar.foo.(*FooService)
+	for _, injected := range []context.Context{bar.ctx, foo.ctx} {
+		assert.Nil(injected.Err())
+		assert.Nil(injected.Done())
+		_, ok := injected.Deadline()
+		assert.False(ok)
+		// while the values are kept
+		assert.Equal("value", injected.Value(contextKey{}))
+		scope, ok := manioc.ScopeFromContext(injected)
+		assert.True(ok)
+		assert.Same(ctr, scope)
+	}
+
+	// the transient services are bound to the resolution
+	ctx, cancel = context.WithCancel(context.Background())
+	transient, err := manioc.ResolveContext[IFooService](ctx, manioc.WithScope(ctr))
+	assert.Nil(err)
+	cancel()
+	assert.ErrorIs(transient.(*FooService).ctx.Err(), context.Canceled)
+}
+
+func Test_ResolveContext_Canceled(t *testing.T) {
+	t.Run("canceled before resolution", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := setup(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := manioc.ResolveContext[IBarService](ctx, manioc.WithScope(ctr))
+		assert.ErrorIs(err, manioc.ErrCanceled)
+		assert.ErrorIs(err, context.Canceled)
+	})
+
+	t.Run("canceled between activations", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctr := manioc.NewContainer()
+		// cancel the context in the constructor of the dependency
+		assert.Nil(manioc.RegisterConstructor[IFooService](func(ctx context.Context) *FooService {
+			cancel()
+			return &FooService{ctx: ctx}
+		}, manioc.WithContainer(ctr)))
+		assert.Nil(manioc.RegisterConstructor[IBarService](func(foo IFooService, bar []IFooService) *BarService {
+			return &BarService{}
+		}, manioc.WithContainer(ctr)))
+
+		_, err := manioc.ResolveContext[IBarService](ctx, manioc.WithScope(ctr))
+		assert.ErrorIs(err, manioc.ErrCanceled)
+		var resolveErr *manioc.ResolveError
+		assert.True(errors.As(err, &resolveErr))
+		assert.Len(resolveErr.Path, 1)
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := setup(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 0)
+		defer cancel()
+		<-ctx.Done()
+		_, err := manioc.ResolveContext[IFooService](ctx, manioc.WithScope(ctr))
+		assert.ErrorIs(err, manioc.ErrCanceled)
+		assert.ErrorIs(err, context.DeadlineExceeded)
+	})
+}
+
+func Test_ResolveContext_ClosedScope(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := setup(t)
+	scope, closeScope := ctr.OpenScope()
+	closeScope()
+	_, err := manioc.ResolveContext[IFooService](context.Background(), manioc.WithScope(scope))
+	assert.ErrorIs(err, manioc.ErrScopeClosed)
+}
@@ -0,0 +1,170 @@
+package manioc_resolve_errors_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+// In this case, we will verify the errors for the following dependencies:
+//  A implements IA
+//    A requires IB via field injection
+//  B implements IB
+//    B requires IC with key=foo via field injection
+//  C implements IC
+//    C is constructed by NewC, which may fail
+
+type IA interface {
+	doA()
+}
+
+type A struct {
+	b IB `manioc:"inject"`
+}
+
+func (a *A) doA() {}
+
+type IB interface {
+	doB()
+}
+
+type B struct {
+	c IC `manioc:"inject,key=foo"`
+}
+
+func (b *B) doB() {}
+
+type IC interface {
+	doC()
+}
+
+type C struct{}
+
+func (c *C) doC() {}
+
+var errNewC = errors.New("failed to create C")
+
+func NewC() (*C, error) {
+	return nil, errNewC
+}
+
+func typeof[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func Test_ResolveError_NotRegistered(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IA, A](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IB, B](manioc.WithContainer(ctr)))
+
+	_, err := manioc.Resolve[IA](manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrNotRegistered)
+	assert.NotErrorIs(err, manioc.ErrAmbiguous)
+
+	var resolveErr *manioc.ResolveError
+	assert.ErrorAs(err, &resolveErr)
+	assert.Equal(typeof[IC](), resolveErr.ServiceType)
+	assert.Equal("foo", resolveErr.ServiceKey)
+	assert.Equal([]manioc.Dependency{
+		{ServiceType: typeof[IA](), ServiceKey: nil},
+		{ServiceType: typeof[IB](), ServiceKey: nil},
+	}, resolveErr.Path)
+	assert.Equal(
+		"no registration found: "+
+			"manioc_resolve_errors_test.IA -> "+
+			"manioc_resolve_errors_test.IB -> "+
+			"manioc_resolve_errors_test.IC(key=foo)",
+		err.Error(),
+	)
+}
+
+func Test_ResolveError_Ambiguous(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IA, A](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IB, B](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IB, B](manioc.WithContainer(ctr)))
+
+	_, err := manioc.Resolve[IA](manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrAmbiguous)
+
+	var resolveErr *manioc.ResolveError
+	assert.ErrorAs(err, &resolveErr)
+	assert.Equal(typeof[IB](), resolveErr.ServiceType)
+	assert.Nil(resolveErr.ServiceKey)
+	assert.Equal([]manioc.Dependency{{ServiceType: typeof[IA](), ServiceKey: nil}}, resolveErr.Path)
+}
+
+func Test_ResolveError_ConstructorFailed(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IA, A](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[IB, B](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterConstructor[IC](NewC, manioc.WithContainer(ctr), manioc.WithRegisterKey("foo")))
+
+	_, err := manioc.Resolve[IA](manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrConstructorFailed)
+	// the error returned by the constructor is wrapped
+	assert.ErrorIs(err, errNewC)
+
+	var resolveErr *manioc.ResolveError
+	assert.ErrorAs(err, &resolveErr)
+	assert.Equal(typeof[IC](), resolveErr.ServiceType)
+	assert.Equal("foo", resolveErr.ServiceKey)
+	assert.Len(resolveErr.Path, 2)
+	assert.Same(errNewC, errors.Unwrap(err))
+
+	// direct resolution also wraps the error
+	_, err = manioc.ResolveFunction[*C](NewC, manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrConstructorFailed)
+	assert.ErrorIs(err, errNewC)
+}
+
+func Test_ResolveError_ScopeClosed(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IC, C](manioc.WithContainer(ctr)))
+	scope, cleanup := ctr.OpenScope()
+	cleanup()
+
+	_, err := manioc.Resolve[IC](manioc.WithScope(scope))
+	assert.ErrorIs(err, manioc.ErrScopeClosed)
+	var resolveErr *manioc.ResolveError
+	assert.ErrorAs(err, &resolveErr)
+	assert.Equal(typeof[IC](), resolveErr.ServiceType)
+
+	_, err = manioc.ResolveInstance(&C{}, manioc.WithScope(scope))
+	assert.ErrorIs(err, manioc.ErrScopeClosed)
+}
+
+type ICycle interface {
+	doCycle()
+}
+
+type Cycle struct {
+	next ICycle `manioc:"inject"`
+}
+
+func (c *Cycle) doCycle() {}
+
+func Test_ResolveError_CircularDependency(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[ICycle, Cycle](manioc.WithContainer(ctr)))
+
+	_, err := manioc.Resolve[ICycle](manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrCircularDependency)
+	var resolveErr *manioc.ResolveError
+	assert.ErrorAs(err, &resolveErr)
+	assert.Equal(typeof[ICycle](), resolveErr.ServiceType)
+	assert.Equal([]manioc.Dependency{{ServiceType: typeof[ICycle](), ServiceKey: nil}}, resolveErr.Path)
+}
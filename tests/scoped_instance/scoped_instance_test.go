@@ -0,0 +1,242 @@
+package manioc_scoped_instance_test
+
+import (
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+// RequestInfo is a value specific to a request
+type RequestInfo struct {
+	ID string
+}
+
+type IHandler interface {
+	handle() string
+}
+
+// Handler implements IHandler, and requires *RequestInfo
+type Handler struct {
+	req *RequestInfo `manioc:"inject"`
+}
+
+func (h *Handler) handle() string {
+	return h.req.ID
+}
+
+func setup(t *testing.T) manioc.Container {
+	t.Helper()
+	ctr := manioc.NewContainer()
+	assert.Nil(t, manioc.Register[IHandler, Handler](
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.ScopedCache),
+	))
+	return ctr
+}
+
+func Test_ScopedInstance(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := setup(t)
+	scope1, closeScope1 := ctr.OpenScope()
+	defer closeScope1()
+	scope2, closeScope2 := ctr.OpenScope()
+	defer closeScope2()
+	assert.Nil(manioc.RegisterScopedInstance(scope1, &RequestInfo{ID: "req1"}))
+	assert.Nil(manioc.RegisterScopedInstance(scope2, &RequestInfo{ID: "req2"}))
+
+	// each scope resolves its own instance
+	h1, err := manioc.Resolve[IHandler](manioc.WithScope(scope1))
+	assert.Nil(err)
+	assert.Equal("req1", h1.handle())
+	h2, err := manioc.Resolve[IHandler](manioc.WithScope(scope2))
+	assert.Nil(err)
+	assert.Equal("req2", h2.handle())
+
+	// the instance is not visible from the container
+	_, err = manioc.Resolve[IHandler](manioc.WithScope(ctr))
+	assert.ErrorIs(err, manioc.ErrNotRegistered)
+	assert.False(manioc.IsRegistered[*RequestInfo](manioc.WithContainer(ctr)))
+	assert.Len(manioc.Registrations(ctr), 1)
+}
+
+func Test_ScopedInstance_Precedence(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := setup(t)
+	assert.Nil(manioc.RegisterInstance(&RequestInfo{ID: "default"}, manioc.WithContainer(ctr)))
+	scope, closeScope := ctr.OpenScope()
+	defer closeScope()
+	assert.Nil(manioc.RegisterScopedInstance(scope, &RequestInfo{ID: "scoped"}))
+	assert.Nil(manioc.RegisterScopedInstance(scope, &RequestInfo{ID: "keyed"}, manioc.WithRegisterKey("key")))
+
+	// the scoped instance takes precedence over the registration in the container
+	req, err := manioc.Resolve[*RequestInfo](manioc.WithScope(scope))
+	assert.Nil(err)
+	assert.Equal("scoped", req.ID)
+	req, err = manioc.Resolve[*RequestInfo](manioc.WithScope(scope), manioc.WithResolveKey("key"))
+	assert.Nil(err)
+	assert.Equal("keyed", req.ID)
+	req, err = manioc.Resolve[*RequestInfo](manioc.WithScope(ctr))
+	assert.Nil(err)
+	assert.Equal("default", req.ID)
+}
+
+func Test_ScopedInstance_CacheMode(t *testing.T) {
+	cases := map[string]struct {
+		mode    manioc.ScopeCacheMode
+		visible bool
+	}{
+		"DefaultCacheMode": {mode: manioc.DefaultCacheMode, visible: false},
+		"InheritCacheMode": {mode: manioc.InheritCacheMode, visible: true},
+		"SyncCacheMode":    {mode: manioc.SyncCacheMode, visible: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			ctr := setup(t)
+			parent, closeParent := ctr.OpenScope()
+			defer closeParent()
+			assert.Nil(manioc.RegisterScopedInstance(parent, &RequestInfo{ID: "parent"}))
+			child, closeChild := parent.OpenScope(manioc.WithCacheMode(c.mode))
+			defer closeChild()
+
+			req, err := manioc.Resolve[*RequestInfo](manioc.WithScope(child))
+			if !c.visible {
+				assert.ErrorIs(err, manioc.ErrNotRegistered)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal("parent", req.ID)
+
+			// the instances registered in the child are not visible to the parent
+			assert.Nil(manioc.RegisterScopedInstance(child, &RequestInfo{ID: "child"}, manioc.WithRegisterKey("child")))
+			assert.Equal("child", manioc.MustResolve[*RequestInfo](manioc.WithScope(child), manioc.WithResolveKey("child")).ID)
+			_, err = manioc.Resolve[*RequestInfo](manioc.WithScope(parent), manioc.WithResolveKey("child"))
+			assert.ErrorIs(err, manioc.ErrNotRegistered)
+		})
+	}
+}
+
+func Test_ScopedInstance_SyncCacheMode(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := setup(t)
+	parent, closeParent := ctr.OpenScope()
+	defer closeParent()
+	for _, id := range []string{"first", "second"} {
+		child, closeChild := parent.OpenScope(manioc.WithCacheMode(manioc.SyncCacheMode))
+		assert.Nil(manioc.RegisterScopedInstance(child, &RequestInfo{ID: id}))
+		assert.Equal(id, manioc.MustResolve[*RequestInfo](manioc.WithScope(child)).ID)
+		closeChild()
+
+		// the instance is discarded when the child is closed, although the cache is shared with the parent
+		_, err := manioc.Resolve[*RequestInfo](manioc.WithScope(parent))
+		assert.ErrorIs(err, manioc.ErrNotRegistered)
+		_, err = manioc.Resolve[*RequestInfo](manioc.WithScope(ctr))
+		assert.ErrorIs(err, manioc.ErrNotRegistered)
+	}
+}
+
+func Test_ScopedInstance_Errors(t *testing.T) {
+	t.Run("closed scope", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := setup(t)
+		scope, closeScope := ctr.OpenScope()
+		assert.Nil(manioc.RegisterScopedInstance(scope, &RequestInfo{ID: "req"}))
+		closeScope()
+		err := manioc.RegisterScopedInstance(scope, &RequestInfo{ID: "req"})
+		assert.ErrorIs(err, manioc.ErrScopeClosed)
+	})
+
+	t.Run("nil instance", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := setup(t)
+		assert.Error(manioc.RegisterScopedInstance[*RequestInfo](ctr, nil))
+	})
+
+	t.Run("captured by singleton", func(t *testing.T) {
+		assert := assert.New(t)
+
+		ctr := manioc.NewContainer()
+		assert.Nil(manioc.Register[IHandler, Handler](manioc.WithContainer(ctr), manioc.WithCachePolicy(manioc.GlobalCache)))
+		scope, closeScope := ctr.OpenScope()
+		defer closeScope()
+		assert.Nil(manioc.RegisterScopedInstance(scope, &RequestInfo{ID: "req"}))
+
+		_, err := manioc.Resolve[IHandler](manioc.WithScope(scope))
+		assert.ErrorIs(err, manioc.ErrCaptiveDependency)
+	})
+}
+
+func Test_ScopedInstance_Declare(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := setup(t)
+	// the instance registered for the scopes is unknown to the container
+	assert.ErrorIs(manioc.Validate(ctr), manioc.ErrNotRegistered)
+
+	// the declared instance is treated as resolved
+	assert.Nil(manioc.DeclareScopedInstance[*RequestInfo](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.DeclareScopedInstance[*RequestInfo](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Validate(ctr))
+	assert.Nil(manioc.Validate(ctr.NewChild()))
+	assert.Nil(manioc.Validate(ctr.Clone()))
+	g, err := manioc.Graph(ctr)
+	assert.Nil(err)
+	assert.Len(g.Nodes, 2)
+	declared := g.Nodes[1]
+	assert.Equal("*manioc_scoped_instance_test.RequestInfo", declared.ServiceType)
+	assert.Equal("instance", declared.Kind)
+	assert.Equal("ScopedCache", declared.CachePolicy)
+	assert.Len(g.Edges, 1)
+	assert.Equal([]string{declared.ID}, g.Edges[0].To)
+	assert.Equal(manioc.EdgeResolved, g.Edges[0].Status)
+
+	// but it is not registered in the container
+	assert.False(manioc.IsRegistered[*RequestInfo](manioc.WithContainer(ctr)))
+	assert.Len(manioc.Registrations(ctr), 1)
+	scope, closeScope := ctr.OpenScope()
+	defer closeScope()
+	_, err = manioc.Resolve[IHandler](manioc.WithScope(scope))
+	assert.ErrorIs(err, manioc.ErrNotRegistered)
+	assert.Nil(manioc.RegisterScopedInstance(scope, &RequestInfo{ID: "req"}))
+	assert.Equal("req", manioc.MustResolve[IHandler](manioc.WithScope(scope)).handle())
+
+	// the declaration is specific to the key
+	assert.Nil(manioc.DeclareScopedInstance[*RequestInfo](manioc.WithContainer(ctr), manioc.WithRegisterKey("key")))
+	assert.False(manioc.IsRegistered[*RequestInfo](manioc.WithContainer(ctr), manioc.WithRegisterKey("key")))
+	g, err = manioc.Graph(ctr)
+	assert.Nil(err)
+	assert.Len(g.Nodes, 3)
+
+	assert.Nil(ctr.Close())
+	assert.ErrorIs(manioc.DeclareScopedInstance[*RequestInfo](manioc.WithContainer(ctr)), manioc.ErrScopeClosed)
+}
+
+func Test_ScopedInstance_DeclareCaptive(t *testing.T) {
+	assert := assert.New(t)
+
+	// the declared instance has ScopedCache lifetime, so it is captured by the singletons
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IHandler, Handler](
+		manioc.WithContainer(ctr),
+		manioc.WithCachePolicy(manioc.GlobalCache),
+	))
+	assert.Nil(manioc.DeclareScopedInstance[*RequestInfo](manioc.WithContainer(ctr)))
+	assert.ErrorIs(manioc.Validate(ctr), manioc.ErrCaptiveDependency)
+
+	// while the scoped services can depend on it, even in StrictCaptiveMode
+	strict := manioc.NewContainer(manioc.WithCaptiveMode(manioc.StrictCaptiveMode))
+	assert.Nil(manioc.Register[IHandler, Handler](
+		manioc.WithContainer(strict),
+		manioc.WithCachePolicy(manioc.ScopedCache),
+	))
+	assert.Nil(manioc.DeclareScopedInstance[*RequestInfo](manioc.WithContainer(strict)))
+	assert.Nil(manioc.Validate(strict))
+}
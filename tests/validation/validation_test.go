@@ -0,0 +1,170 @@
+package manioc_validation_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/fuzmish/manioc"
+	"github.com/stretchr/testify/assert"
+)
+
+// In this case, we will validate the following dependencies:
+//  A implements IA
+//    A requires IB via field injection
+//  B implements IB
+//    B requires IC via constructor injection
+//  C implements IC
+//    C requires []ID via field injection
+//  D implements ID
+
+type IA interface {
+	doA()
+}
+
+type A struct {
+	b IB `manioc:"inject"`
+}
+
+func (a *A) doA() {}
+
+type IB interface {
+	doB()
+}
+
+type B struct {
+	c IC
+}
+
+func (b *B) doB() {}
+
+func NewB(c IC) *B {
+	return &B{c: c}
+}
+
+type IC interface {
+	doC()
+}
+
+type C struct {
+	ds []ID `manioc:"inject"`
+}
+
+func (c *C) doC() {}
+
+type ID interface {
+	doD()
+}
+
+type D struct{}
+
+func (d *D) doD() {}
+
+func typeof[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func Test_Validate_Valid(t *testing.T) {
+	assert := assert.New(t)
+
+	var constructed bool
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IA, A](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterConstructor[IB](
+		func(c IC) *B {
+			constructed = true
+			return NewB(c)
+		},
+		manioc.WithContainer(ctr),
+	))
+	assert.Nil(manioc.Register[IC, C](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[ID, D](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[ID, D](manioc.WithContainer(ctr)))
+
+	assert.Nil(manioc.Validate(ctr))
+	// the validation does not instantiate anything
+	assert.False(constructed)
+}
+
+func Test_Validate_Invalid(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[IA, A](manioc.WithContainer(ctr)))
+	// IB is ambiguous
+	assert.Nil(manioc.RegisterConstructor[IB](NewB, manioc.WithContainer(ctr)))
+	assert.Nil(manioc.RegisterConstructor[IB](NewB, manioc.WithContainer(ctr)))
+	// []ID is missing
+	assert.Nil(manioc.Register[IC, C](manioc.WithContainer(ctr)))
+
+	err := manioc.Validate(ctr)
+	assert.ErrorIs(err, manioc.ErrAmbiguous)
+	assert.ErrorIs(err, manioc.ErrNotRegistered)
+
+	// all problems are reported at once
+	var aggregated *manioc.AggregateError
+	assert.ErrorAs(err, &aggregated)
+	assert.Len(aggregated.Errors, 2)
+
+	details := make(map[error]*manioc.ResolveError)
+	for _, err := range aggregated.Errors {
+		var resolveErr *manioc.ResolveError
+		assert.ErrorAs(err, &resolveErr)
+		details[resolveErr.Err] = resolveErr
+	}
+	assert.Equal(typeof[IB](), details[manioc.ErrAmbiguous].ServiceType)
+	assert.Equal([]manioc.Dependency{{ServiceType: typeof[IA](), ServiceKey: nil}}, details[manioc.ErrAmbiguous].Path)
+	assert.Equal(typeof[[]ID](), details[manioc.ErrNotRegistered].ServiceType)
+	assert.Equal([]manioc.Dependency{
+		{ServiceType: typeof[IB](), ServiceKey: nil},
+		{ServiceType: typeof[IC](), ServiceKey: nil},
+	}, details[manioc.ErrNotRegistered].Path)
+}
+
+type ICycle interface {
+	doCycle()
+}
+
+type Cycle struct {
+	next ICycle `manioc:"inject,key=next"`
+}
+
+func (c *Cycle) doCycle() {}
+
+func Test_Validate_CircularDependency(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[ICycle, Cycle](manioc.WithContainer(ctr)))
+	assert.Nil(manioc.Register[ICycle, Cycle](manioc.WithContainer(ctr), manioc.WithRegisterKey("next")))
+
+	err := manioc.Validate(ctr)
+	assert.ErrorIs(err, manioc.ErrCircularDependency)
+	var aggregated *manioc.AggregateError
+	assert.ErrorAs(err, &aggregated)
+	// the same cycle is reported only once
+	assert.Len(aggregated.Errors, 1)
+	assert.Equal(
+		"circular dependency detected: "+
+			"manioc_validation_test.ICycle -> "+
+			"manioc_validation_test.ICycle(key=next) -> "+
+			"manioc_validation_test.ICycle(key=next)",
+		aggregated.Errors[0].Error(),
+	)
+}
+
+type InvalidTag struct {
+	value any `manioc:"unknown"`
+}
+
+func Test_Validate_InvalidTag(t *testing.T) {
+	assert := assert.New(t)
+
+	ctr := manioc.NewContainer()
+	assert.Nil(manioc.Register[*InvalidTag, *InvalidTag](manioc.WithContainer(ctr)))
+
+	err := manioc.Validate(ctr)
+	assert.Error(err)
+	var resolveErr *manioc.ResolveError
+	assert.False(errors.As(err, &resolveErr))
+}
@@ -1,6 +1,8 @@
 package manioc
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 )
 
@@ -19,6 +21,19 @@ const (
 	NeverCache
 )
 
+func (p CachePolicy) String() string {
+	switch p {
+	case GlobalCache:
+		return "GlobalCache"
+	case ScopedCache:
+		return "ScopedCache"
+	case NeverCache:
+		return "NeverCache"
+	default:
+		return fmt.Sprintf("CachePolicy(%d)", int(p))
+	}
+}
+
 // ScopeCacheMode is an enumeration type that configures the behavior of
 // the scope with respect to its instance cache.
 type ScopeCacheMode int
@@ -35,32 +50,140 @@ const (
 	SyncCacheMode
 )
 
+// CaptiveMode is an enumerated type that configures how the container handles captive dependencies,
+// i.e. the dependencies with a cache policy shorter-lived than the one of the dependent service.
+// Captive dependencies live as long as the dependent service, which is usually unintended.
+type CaptiveMode int
+
+const (
+	// The resolution fails if a ScopedCache service is captured by a GlobalCache service.
+	// Capturing NeverCache services is allowed.
+	DefaultCaptiveMode CaptiveMode = iota
+	// The resolution fails if any service is captured by a longer-lived service,
+	// i.e. ScopedCache or NeverCache services by GlobalCache services,
+	// and NeverCache services by ScopedCache services.
+	StrictCaptiveMode
+	// Captive dependencies are not detected.
+	IgnoreCaptiveMode
+)
+
+// ConflictPolicy is an enumerated type that specifies how a registration is handled
+// when the service has already been registered with the same key in the container.
+// The registrations inherited from the parent container are not considered as conflicts.
+type ConflictPolicy int
+
+const (
+	// The registration is added to the existing ones. The service can be resolved with ResolveMany,
+	// while Resolve fails with ErrAmbiguous.
+	Append ConflictPolicy = iota
+	// The existing registrations are removed, and the service is resolved with the new one.
+	Replace
+	// The registration is ignored, and the service is resolved with the existing one.
+	KeepExisting
+	// The registration fails with ErrDuplicateRegistration.
+	FailOnDuplicate
+)
+
+// the conflict policy of the registration is not specified, so the default of the container is used
+const defaultConflictPolicy ConflictPolicy = -1
+
+func (p ConflictPolicy) String() string {
+	switch p {
+	case Append:
+		return "Append"
+	case Replace:
+		return "Replace"
+	case KeepExisting:
+		return "KeepExisting"
+	case FailOnDuplicate:
+		return "FailOnDuplicate"
+	default:
+		return fmt.Sprintf("ConflictPolicy(%d)", int(p))
+	}
+}
+
 type registryKey struct {
 	serviceType reflect.Type
 	serviceKey  any
 }
 
+func (k registryKey) String() string {
+	if k.serviceKey == nil {
+		return k.serviceType.String()
+	}
+	return fmt.Sprintf("%s(key=%v)", k.serviceType, k.serviceKey)
+}
+
+// dependency is a service that an activator requires to activate an instance.
+type dependency struct {
+	key registryKey
+	// the index of the constructor argument, or -1 for field injection
+	argIndex int
+	// the name of the injected field, or empty for constructor injection
+	fieldName string
+	// whether the dependency may not be registered
+	optional bool
+}
+
 type activator interface {
 	activate(ctx resolveContext) (any, error)
+	// returns the dependencies required by this activator, without activating any instances
+	dependencies() ([]dependency, error)
+}
+
+// typedActivator is an activator that knows the type of the instances it activates.
+type typedActivator interface {
+	activator
+	instanceType() reflect.Type
 }
 
 type resolveContext interface {
 	resolve(key registryKey) (any, error)
-	setCache(key any, value any, policy CachePolicy)
-	getCache(key any, policy CachePolicy) (any, bool)
+	// resolve the key, or return false if the key is not registered
+	resolveOptional(key registryKey) (any, bool, error)
+	// get the cached instance of the registration in the owner registry, or create and cache a new one.
+	// the context given to `create` is the one to resolve the dependencies of the new instance.
+	getOrCreateCache(
+		owner *registry,
+		key any,
+		policy CachePolicy,
+		disposable bool,
+		create func(ctx resolveContext) (any, error),
+	) (any, error)
+	// create an error for the failure to activate the service being resolved
+	newError(err error, cause error) error
+	// returns the resolveContext whose resolutions are bound to the context.Context
+	withContext(ctx context.Context) resolveContext
 }
 
 type registerContext interface {
-	register(key registryKey, entry activator) error
+	register(key registryKey, base activator, options *registerOptions) error
+	declareScoped(key registryKey)
 	isRegistered(key registryKey) bool
 	unregister(key registryKey) bool
+	registrations() []Registration
+	graph() (*DependencyGraph, error)
+	decorate(key registryKey, d *decorator) error
+	observe(key registryKey, fn func(instance any)) error
+	validate() error
+	activateSingletons(ctx context.Context) ([]any, error)
+}
+
+type scopedRegisterContext interface {
+	registerScoped(key registryKey, base activator, options *registerOptions) error
 }
 
 // Scope is an interface that expresses the cache scope of a container.
 type Scope interface {
 	getResolveContext() resolveContext
+	getScopedRegisterContext() scopedRegisterContext
 	OpenScope(opts ...OpenScopeOption) (Scope, func())
-	closeScope()
+	// Close the scope and dispose the instances it owns.
+	// Instances implementing Disposable or io.Closer are disposed in reverse creation order,
+	// and the errors are returned as an *AggregateError.
+	// After the scope is closed, the resolution requests for the scope will fail.
+	Close() error
+	closeScope() error
 }
 
 // Container is an interface for storing dependencies.
@@ -68,4 +191,20 @@ type Scope interface {
 type Container interface {
 	Scope
 	getRegisterContext() registerContext
+	getModuleRegistry() *moduleRegistry
+	getLifecycle() *lifecycle
+	// Create a copy of this container, including the cached instances.
+	snapshot() Container
+	// Create a child container, which inherits the registrations of this container.
+	// The registrations in the child container take precedence over the inherited ones,
+	// and they do not affect this container. The instances of the inherited registrations
+	// with GlobalCache policy are shared with this container.
+	// The child container is closed when this container is closed.
+	NewChild() Container
+	// Create a new container with the copies of the registrations, the decorators, the installed modules
+	// and the lifecycle hooks of this container. The changes of the registrations in either container
+	// do not affect the other. The cached instances are not copied, so the clone creates its own instances.
+	// The clone of a child container inherits the registrations of the same parent,
+	// but it is not closed when the parent is closed.
+	Clone() Container
 }
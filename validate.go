@@ -0,0 +1,155 @@
+package manioc
+
+import (
+	"fmt"
+)
+
+// Validate checks that all dependencies registered in the container can be resolved,
+// without instantiating any of them. It reports all missing, ambiguous and circular dependencies
+// found in the container as an *AggregateError, which consists of *ResolveError.
+// Captive dependencies are also reported according to the CaptiveMode of the container.
+//
+// Note that the validation is based on the static types; for example, the fields of an instance
+// returned by a constructor are inspected based on the return type of the constructor.
+func Validate(container Container) error {
+	ctx := container.getRegisterContext()
+	if ctx == nil {
+		return newScopeClosedError(nil, nil)
+	}
+	return ctx.validate()
+}
+
+type validator struct {
+	registry    *registry
+	captiveMode CaptiveMode
+	// registrations whose dependencies have already been validated
+	visited map[*registration]struct{}
+	// the keys being validated, from the outermost one
+	path []registryKey
+	// the keys in path before this index are resolved lazily, so they are not part of cycles
+	deferred int
+	errs     []error
+}
+
+func (c *defaultContext) validate() error {
+	v := &validator{
+		registry:    c.registry,
+		captiveMode: c.captiveMode,
+		visited:     make(map[*registration]struct{}),
+		path:        make([]registryKey, 0),
+		deferred:    0,
+		errs:        make([]error, 0),
+	}
+	keys, entries := c.registry.all()
+	for _, key := range keys {
+		for _, entry := range entries[key] {
+			v.visit(key, entry)
+		}
+	}
+	for _, key := range keys {
+		for _, entry := range entries[key] {
+			v.checkCaptive(key, entry)
+		}
+	}
+	return newAggregateError(v.errs)
+}
+
+func (v *validator) report(key registryKey, err error, cause error) {
+	path := make([]Dependency, len(v.path))
+	for i, key := range v.path {
+		path[i] = Dependency{ServiceType: key.serviceType, ServiceKey: key.serviceKey}
+	}
+	v.errs = append(v.errs, &ResolveError{
+		Err:         err,
+		ServiceType: key.serviceType,
+		ServiceKey:  key.serviceKey,
+		Path:        path,
+		Cause:       cause,
+	})
+}
+
+// Validate the dependencies of the activator registered with the key.
+func (v *validator) visit(key registryKey, entry *registration) {
+	// detect circular dependency
+	for _, k := range v.path[v.deferred:] {
+		if k == key {
+			v.report(key, ErrCircularDependency, nil)
+			return
+		}
+	}
+	if _, ok := v.visited[entry]; ok {
+		return
+	}
+	v.visited[entry] = struct{}{}
+	deps, err := entry.dependenciesFor(key)
+	if err != nil {
+		v.errs = append(v.errs, fmt.Errorf("invalid registration for `%s`: %w", key, err))
+		return
+	}
+	v.path = append(v.path, key)
+	defer func() { v.path = v.path[:len(v.path)-1] }()
+	for _, dep := range deps {
+		if dep.optional {
+			v.resolveOptional(dep.key)
+		} else {
+			v.resolve(dep.key)
+		}
+	}
+}
+
+// Validate the resolution of the key, in the same way as resolveFrame.resolve.
+func (v *validator) resolve(key registryKey) {
+	target := v.registry.lookupTarget(key)
+	switch target.kind {
+	case targetRegistered:
+		if len(target.entries) > 1 {
+			v.report(key, ErrAmbiguous, nil)
+			return
+		}
+		v.visit(key, target.entries[0])
+	case targetAll:
+		v.resolveAll(key, target)
+	case targetDeferred:
+		v.resolveDeferred(key, target.key)
+	case targetOptional:
+		v.path = append(v.path, key)
+		defer func() { v.path = v.path[:len(v.path)-1] }()
+		v.resolveOptional(target.key)
+	case targetContext:
+		break
+	case targetNotRegistered:
+		v.report(key, ErrNotRegistered, nil)
+	}
+}
+
+func (v *validator) resolveAll(key registryKey, target dependencyTarget) {
+	if len(target.entries) == 0 {
+		v.report(key, ErrNotRegistered, nil)
+		return
+	}
+	v.path = append(v.path, key)
+	defer func() { v.path = v.path[:len(v.path)-1] }()
+	for _, entry := range target.entries {
+		v.visit(target.key, entry)
+	}
+}
+
+func (v *validator) resolveDeferred(key registryKey, elemKey registryKey) {
+	v.path = append(v.path, key)
+	deferred := v.deferred
+	v.deferred = len(v.path)
+	defer func() {
+		v.path = v.path[:len(v.path)-1]
+		v.deferred = deferred
+	}()
+	v.resolve(elemKey)
+}
+
+// Validate the resolution of the key, except that the key itself may not be registered.
+func (v *validator) resolveOptional(key registryKey) {
+	num := len(v.errs)
+	v.resolve(key)
+	if len(v.errs) == num+1 && isKeyNotRegistered(v.errs[num], key, len(v.path)) {
+		v.errs = v.errs[:num]
+	}
+}